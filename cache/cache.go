@@ -0,0 +1,19 @@
+package cache
+
+import (
+	"path/filepath"
+
+	"github.com/apxxxxxxe/rfcui/config"
+)
+
+var (
+	DataPath = config.DefaultDataPath()
+	// where feeds were cached as files before the database; read once to migrate them
+	CachePath = filepath.Join(DataPath, "cache")
+)
+
+// Configure points the paths at those of conf.
+func Configure(conf *config.Config) {
+	DataPath = conf.DataPath
+	CachePath = conf.CachePath
+}
@@ -0,0 +1,307 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/apxxxxxxe/rfcui/config"
+	"github.com/apxxxxxxe/rfcui/core"
+	fd "github.com/apxxxxxxe/rfcui/feed"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrUnknownCommand    = errors.New("unknown command")
+	ErrInvalidArguments  = errors.New("invalid arguments")
+	ErrAlreadySubscribed = errors.New("already subscribed")
+	ErrFeedNotFound      = errors.New("no such feed or group")
+)
+
+const usage = `usage: rfcui [--config <path>] [<command> [<args>]]
+
+Without a command, rfcui starts the TUI.
+The config is read from <path>, $RFCUI_CONFIG or the data directory in this order.
+
+commands:
+  add <url> [<title>]                  subscribe to a feed
+  list [-json] feeds                   list feeds
+  list [-json] groups                  list groups
+  list [-json] items [<title or url>]  list items of all feeds, or of a feed or group
+  search [-json] <words>               search all items, archived ones included
+  update                               refresh all feeds
+  export [<path>]                      export feeds and groups as OPML to path or stdout
+`
+
+type feedEntry struct {
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Link   string `json:"link"`
+	Items  int    `json:"items"`
+	Unread int    `json:"unread"`
+}
+
+type groupEntry struct {
+	Title  string   `json:"title"`
+	Feeds  []string `json:"feeds"`
+	Items  int      `json:"items"`
+	Unread int      `json:"unread"`
+}
+
+type itemEntry struct {
+	ID          string    `json:"id"`
+	Feed        string    `json:"feed"`
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	Published   time.Time `json:"published"`
+	DateUnknown bool      `json:"date_unknown,omitempty"`
+	Read        bool      `json:"read"`
+}
+
+// Run executes the command in args with the settings of conf, writing its output to out.
+func Run(args []string, out io.Writer, conf *config.Config) error {
+	if len(args) == 0 {
+		return ErrInvalidArguments
+	}
+
+	m := core.NewManagerWithConfig(conf)
+	defer m.Close()
+
+	switch args[0] {
+	case "add":
+		return add(m, args[1:], out)
+	case "list":
+		return list(m, args[1:], out)
+	case "search":
+		return search(m, args[1:], out)
+	case "update":
+		return update(m, out)
+	case "export":
+		return export(m, args[1:], out)
+	case "help", "-h", "-help", "--help":
+		PrintUsage(out)
+		return nil
+	default:
+		PrintUsage(os.Stderr)
+		return errors.Wrap(ErrUnknownCommand, args[0])
+	}
+}
+
+// load loads the feeds, warning of the broken records put into quarantine.
+// The commands which only read load them with LoadReadOnly instead, so that they never write.
+func load(m *core.Manager) error {
+	if err := m.Load(); err != nil {
+		return err
+	}
+	for _, c := range m.Corruptions {
+		fmt.Fprintln(os.Stderr, "warning: quarantined broken", c)
+	}
+	return nil
+}
+
+func add(m *core.Manager, args []string, out io.Writer) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.Wrap(ErrInvalidArguments, "add <url> [<title>]")
+	}
+	url := args[0]
+	title := ""
+	if len(args) == 2 {
+		title = args[1]
+	}
+
+	if err := load(m); err != nil {
+		return err
+	}
+	if m.FindFeed(url) != nil {
+		return errors.Wrap(ErrAlreadySubscribed, url)
+	}
+
+	f, err := m.AddFeed(url)
+	if err != nil {
+		return err
+	}
+	if title != "" {
+		if err := m.RenameFeed(f, title); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(out, "Added", f.Title)
+	return nil
+}
+
+func list(m *core.Manager, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
+	if len(args) < 1 {
+		return errors.Wrap(ErrInvalidArguments, "list feeds|groups|items")
+	}
+
+	if err := m.LoadReadOnly(); err != nil {
+		return err
+	}
+	if err := m.UpdateGroups(); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "feeds":
+		entries := []feedEntry{}
+		for _, f := range m.Feeds {
+			feedLink, _ := f.GetFeedLink()
+			entries = append(entries, feedEntry{f.Title, feedLink, f.Link, len(f.Items), f.UnreadCount()})
+		}
+		if *asJSON {
+			return writeJSON(out, entries)
+		}
+		for _, e := range entries {
+			fmt.Fprintf(out, "%d\t%s\t%s\n", e.Unread, e.Title, e.URL)
+		}
+	case "groups":
+		entries := []groupEntry{}
+		for _, g := range m.Groups {
+			entries = append(entries, groupEntry{g.Title, g.FeedLinks, len(g.Items), g.UnreadCount()})
+		}
+		if *asJSON {
+			return writeJSON(out, entries)
+		}
+		for _, e := range entries {
+			fmt.Fprintf(out, "%d\t%s\t%s\n", e.Unread, e.Title, strings.Join(e.Feeds, " "))
+		}
+	case "items":
+		var target *fd.Feed
+		if len(args) > 1 {
+			target = findFeed(m, args[1])
+			if target == nil {
+				target = m.FindGroup(args[1])
+			}
+			if target == nil && args[1] == core.StarredTitle {
+				target = m.Starred
+			}
+			if target == nil {
+				return errors.Wrap(ErrFeedNotFound, args[1])
+			}
+		} else {
+			target = &fd.Feed{FeedLinks: []string{}}
+			for _, f := range m.Feeds {
+				feedLink, _ := f.GetFeedLink()
+				target.FeedLinks = append(target.FeedLinks, feedLink)
+			}
+			target.CollectItems(m.Feeds)
+		}
+
+		return writeItems(out, target.Items, *asJSON)
+	default:
+		return errors.Wrap(ErrInvalidArguments, "list feeds|groups|items")
+	}
+	return nil
+}
+
+func search(m *core.Manager, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.Wrap(ErrInvalidArguments, "search <words>")
+	}
+
+	if err := m.LoadReadOnly(); err != nil {
+		return err
+	}
+	items := m.Search(strings.Join(flags.Args(), " "))
+	return writeItems(out, items, *asJSON)
+}
+
+func update(m *core.Manager, out io.Writer) error {
+	if err := load(m); err != nil {
+		return err
+	}
+
+	// events of the update come from its worker goroutines one at a time
+	failed := []string{}
+	m.Subscribe(func(e core.Event) {
+		if e.Type == core.FeedUpdated && e.Err != nil {
+			feedLink, _ := e.Feed.GetFeedLink()
+			failed = append(failed, fmt.Sprint(feedLink, ": ", e.Err))
+		}
+	})
+	if err := m.UpdateAll(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Updated %d/%d feeds.\n", len(m.Feeds)-len(failed), len(m.Feeds))
+	if len(failed) > 0 {
+		for _, line := range failed {
+			fmt.Fprintln(os.Stderr, "failed to update:", line)
+		}
+		return fd.ErrGettingFeedFailed
+	}
+	return nil
+}
+
+func export(m *core.Manager, args []string, out io.Writer) error {
+	if len(args) > 1 {
+		return errors.Wrap(ErrInvalidArguments, "export [<path>]")
+	}
+
+	if err := m.LoadReadOnly(); err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		return m.ExportOPML(args[0])
+	}
+	return m.OPML().Write(out)
+}
+
+func writeItems(out io.Writer, items []*fd.Item, asJSON bool) error {
+	if asJSON {
+		entries := []itemEntry{}
+		for _, item := range items {
+			entries = append(entries, itemEntry{item.ID, item.Belong, item.Title, item.Link, item.PubDate, item.UnknownDate, item.Read})
+		}
+		return writeJSON(out, entries)
+	}
+	for _, item := range items {
+		status := "read"
+		if !item.Read {
+			status = "unread"
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", item.FormatDate(), status, item.Title, item.Link)
+	}
+	return nil
+}
+
+// findFeed returns the feed whose feed link or title is key.
+func findFeed(m *core.Manager, key string) *fd.Feed {
+	if f := m.FindFeed(key); f != nil {
+		return f
+	}
+	for _, f := range m.Feeds {
+		if f.Title == key {
+			return f
+		}
+	}
+	return nil
+}
+
+func writeJSON(out io.Writer, v interface{}) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func PrintUsage(out io.Writer) {
+	fmt.Fprint(out, usage)
+}
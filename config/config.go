@@ -0,0 +1,291 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/filter"
+	"github.com/apxxxxxxe/rfcui/opener"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gdamore/tcell/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	dataRoot = "rfcui"
+	fileName = "config.toml"
+	EnvPath  = "RFCUI_CONFIG"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+type Config struct {
+	DataPath  string `toml:"data_path"`
+	CachePath string `toml:"cache_path"`
+	// where enclosures are downloaded to; downloads in the data path if empty
+	DownloadPath string `toml:"download_path"`
+	// command to open links with; $BROWSER is used if empty
+	Browser string `toml:"browser"`
+	// command that copies its standard input to the clipboard; detected if empty
+	Clipboard string  `toml:"clipboard"`
+	Timezone  string  `toml:"timezone"`
+	Dates     Dates   `toml:"dates"`
+	Refresh   Refresh `toml:"refresh"`
+	Archive   Archive `toml:"archive"`
+	Layout    Layout  `toml:"layout"`
+	// rules choosing how to open links, tried in order before the browser
+	Openers []*opener.Rule `toml:"openers"`
+	// rules dropping, hiding, marking or starring items, applied in order on every refresh
+	Filters []*filter.Rule `toml:"filters"`
+
+	location *time.Location
+}
+
+// Dates holds the layouts of dates as in time.Format, or "relative" for ones like "3h ago".
+type Dates struct {
+	// shown before the titles of items; empty hides them
+	Items       string `toml:"items"`
+	Description string `toml:"description"`
+}
+
+type Refresh struct {
+	Concurrency int `toml:"concurrency"`
+	PerHost     int `toml:"per_host"`
+	// interval of background refreshes, or 0 to disable them
+	Interval time.Duration `toml:"interval"`
+}
+
+// Archive is how long the items which have left their feeds are kept.
+type Archive struct {
+	fd.Retention
+	// limits of the feeds whose url or title matches, in place of those above
+	Feeds []*FeedRetention `toml:"feeds"`
+}
+
+type FeedRetention struct {
+	Feed     string        `toml:"feed"`
+	MaxItems int           `toml:"max_items"`
+	MaxAge   time.Duration `toml:"max_age"`
+
+	feed *regexp.Regexp
+}
+
+// RetentionOf returns the retention of the feed of feedLink and title.
+func (a Archive) RetentionOf(feedLink, title string) fd.Retention {
+	r := a.Retention
+	for _, f := range a.Feeds {
+		if f.feed != nil && (f.feed.MatchString(feedLink) || f.feed.MatchString(title)) {
+			r.MaxItems = f.MaxItems
+			r.MaxAge = f.MaxAge
+			break
+		}
+	}
+	return r
+}
+
+// Layout holds the proportions of the panes and the color of the focused one.
+type Layout struct {
+	SideWidth         int    `toml:"side_width"`
+	MainWidth         int    `toml:"main_width"`
+	GroupsHeight      int    `toml:"groups_height"`
+	FeedsHeight       int    `toml:"feeds_height"`
+	InfoHeight        int    `toml:"info_height"`
+	ItemsHeight       int    `toml:"items_height"`
+	DescriptionHeight int    `toml:"description_height"`
+	SelectingColor    string `toml:"selecting_color"`
+}
+
+func DefaultDataPath() string {
+	configDir, _ := os.UserConfigDir()
+	return filepath.Join(configDir, dataRoot)
+}
+
+// DefaultPath returns the path of the config file used when none is given.
+func DefaultPath() string {
+	return filepath.Join(DefaultDataPath(), fileName)
+}
+
+func Default() *Config {
+	return &Config{
+		DataPath: DefaultDataPath(),
+		Timezone: "Local",
+		Dates: Dates{
+			Description: "2006/01/02 15:04:05",
+		},
+		Refresh: Refresh{
+			Concurrency: 8,
+			PerHost:     2,
+			Interval:    30 * time.Minute,
+		},
+		Archive: Archive{
+			Retention: fd.Retention{
+				MaxItems:    500,
+				KeepStarred: true,
+			},
+		},
+		Layout: Layout{
+			SideWidth:         1,
+			MainWidth:         2,
+			GroupsHeight:      2,
+			FeedsHeight:       2,
+			InfoHeight:        1,
+			ItemsHeight:       3,
+			DescriptionHeight: 1,
+			SelectingColor:    "green",
+		},
+	}
+}
+
+// Load reads the config file at path over the defaults.
+// An empty path means $RFCUI_CONFIG, or the default path where a missing file is not an error.
+func Load(path string) (*Config, error) {
+	if path == "" {
+		path = os.Getenv(EnvPath)
+	}
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+	}
+
+	conf := Default()
+	md, err := toml.DecodeFile(path, conf)
+	if os.IsNotExist(err) {
+		if explicit {
+			return nil, err
+		}
+		return conf, conf.validate()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+
+	problems := []string{}
+	for _, key := range md.Undecoded() {
+		problems = append(problems, fmt.Sprintf("unknown key %q", key.String()))
+	}
+	if err := conf.validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s: %s", ErrInvalidConfig, path, strings.Join(problems, "; "))
+	}
+	return conf, nil
+}
+
+func (conf *Config) validate() error {
+	problems := []string{}
+
+	conf.DataPath = expandHome(conf.DataPath)
+	if conf.DataPath == "" {
+		problems = append(problems, "data_path is empty")
+	}
+	conf.CachePath = expandHome(conf.CachePath)
+	if conf.CachePath == "" {
+		conf.CachePath = filepath.Join(conf.DataPath, "cache")
+	}
+	conf.DownloadPath = expandHome(conf.DownloadPath)
+	if conf.DownloadPath == "" {
+		conf.DownloadPath = filepath.Join(conf.DataPath, "downloads")
+	}
+
+	location, err := time.LoadLocation(conf.Timezone)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("unknown timezone %q", conf.Timezone))
+	}
+	conf.location = location
+
+	if conf.Dates.Description == "" {
+		problems = append(problems, "dates.description is empty")
+	}
+
+	for i, rule := range conf.Openers {
+		if err := rule.Compile(); err != nil {
+			problems = append(problems, fmt.Sprintf("openers[%d]: %v", i, err))
+		}
+	}
+	for i, rule := range conf.Filters {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("filters[%d]", i)
+		}
+		if err := rule.Compile(); err != nil {
+			problems = append(problems, fmt.Sprintf("filters[%d]: %v", i, err))
+		}
+	}
+
+	if conf.Refresh.Concurrency < 1 {
+		problems = append(problems, "refresh.concurrency must be at least 1")
+	}
+	if conf.Refresh.PerHost < 1 {
+		problems = append(problems, "refresh.per_host must be at least 1")
+	}
+	if conf.Refresh.Interval < 0 {
+		problems = append(problems, "refresh.interval must not be negative")
+	}
+
+	if conf.Archive.MaxItems < 0 || conf.Archive.MaxAge < 0 {
+		problems = append(problems, "archive.max_items and archive.max_age must not be negative")
+	}
+	for i, f := range conf.Archive.Feeds {
+		if f.feed, err = regexp.Compile("(?i)" + f.Feed); err != nil || f.Feed == "" {
+			problems = append(problems, fmt.Sprintf("archive.feeds[%d]: invalid feed pattern %q", i, f.Feed))
+		}
+		if f.MaxItems < 0 || f.MaxAge < 0 {
+			problems = append(problems, fmt.Sprintf("archive.feeds[%d]: max_items and max_age must not be negative", i))
+		}
+	}
+
+	sizes := []struct {
+		key   string
+		value int
+	}{
+		{"layout.side_width", conf.Layout.SideWidth},
+		{"layout.main_width", conf.Layout.MainWidth},
+		{"layout.groups_height", conf.Layout.GroupsHeight},
+		{"layout.feeds_height", conf.Layout.FeedsHeight},
+		{"layout.info_height", conf.Layout.InfoHeight},
+		{"layout.items_height", conf.Layout.ItemsHeight},
+		{"layout.description_height", conf.Layout.DescriptionHeight},
+	}
+	for _, size := range sizes {
+		if size.value < 1 {
+			problems = append(problems, size.key+" must be at least 1")
+		}
+	}
+	if conf.SelectingColor() == tcell.ColorDefault {
+		problems = append(problems, fmt.Sprintf("unknown color %q in layout.selecting_color", conf.Layout.SelectingColor))
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Location returns the timezone dates are shown in.
+func (conf *Config) Location() *time.Location {
+	if conf.location == nil {
+		return time.Local
+	}
+	return conf.location
+}
+
+func (conf *Config) SelectingColor() tcell.Color {
+	return tcell.GetColor(conf.Layout.SelectingColor)
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
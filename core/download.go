@@ -0,0 +1,260 @@
+package core
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apxxxxxxe/rfcui/cache"
+	fd "github.com/apxxxxxxe/rfcui/feed"
+
+	"github.com/pkg/errors"
+)
+
+const progressInterval = 500 * time.Millisecond
+
+var (
+	ErrNoEnclosure    = errors.New("nothing to download in the item")
+	ErrDownloadFailed = errors.New("failed to download")
+)
+
+// downloads of big files must not time out
+var downloadClient = &http.Client{}
+
+type download struct {
+	item      *fd.Item
+	enclosure *fd.Enclosure
+}
+
+// Download queues the enclosures of item not downloaded yet.
+// They are downloaded one at a time in the background into DownloadPath,
+// emitting DownloadProgress and then DownloadFinished events.
+func (m *Manager) Download(item *fd.Item) error {
+	m.downloadMu.Lock()
+	defer m.downloadMu.Unlock()
+
+	queued := 0
+	for _, e := range item.Enclosures {
+		if m.queued[e.URL] {
+			continue
+		}
+		if e.Path != "" {
+			// downloaded again if the file has gone
+			if _, err := os.Stat(e.Path); err == nil {
+				continue
+			}
+			e.Path = ""
+		}
+		m.downloads = append(m.downloads, download{item, e})
+		m.queued[e.URL] = true
+		queued++
+	}
+	if queued == 0 {
+		return ErrNoEnclosure
+	}
+
+	if !m.downloading {
+		m.downloading = true
+		go m.runDownloads()
+	}
+	return nil
+}
+
+// QueuedDownloads returns the number of enclosures waiting or being downloaded.
+func (m *Manager) QueuedDownloads() int {
+	m.downloadMu.Lock()
+	defer m.downloadMu.Unlock()
+	return len(m.queued)
+}
+
+func (m *Manager) runDownloads() {
+	for {
+		m.downloadMu.Lock()
+		if len(m.downloads) == 0 {
+			m.downloading = false
+			m.downloadMu.Unlock()
+			return
+		}
+		d := m.downloads[0]
+		m.downloads = m.downloads[1:]
+		m.downloadMu.Unlock()
+
+		err := m.fetchEnclosure(d)
+
+		m.downloadMu.Lock()
+		delete(m.queued, d.enclosure.URL)
+		m.downloadMu.Unlock()
+
+		if err != nil {
+			var title string
+			m.sync(func() {
+				title = d.item.Title
+			})
+			err = fmt.Errorf("%w: %s: %v", ErrDownloadFailed, title, err)
+		}
+		m.emit(Event{Type: DownloadFinished, Item: d.item, Err: err})
+	}
+}
+
+// fetchEnclosure downloads the enclosure, resuming a partial download if any, and records where it went.
+func (m *Manager) fetchEnclosure(d download) error {
+	var dest string
+	m.sync(func() {
+		dest = m.downloadDest(d)
+	})
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	part := dest + ".part"
+
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, d.enclosure.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", fd.UserAgent)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flag := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		flag |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// the partial download is already complete
+		return m.finishDownload(d, part, dest)
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		// the server does not support ranges
+		offset = 0
+		flag |= os.O_TRUNC
+	default:
+		return errors.New(resp.Status)
+	}
+
+	total := d.enclosure.Length
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+
+	f, err := os.OpenFile(part, flag, 0644)
+	if err != nil {
+		return err
+	}
+	progress := &progressReader{
+		reader: resp.Body,
+		done:   offset,
+		report: func(done int64) {
+			m.emit(Event{Type: DownloadProgress, Item: d.item, Done: int(done), Total: int(total)})
+		},
+	}
+	if _, err := io.Copy(f, progress); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return m.finishDownload(d, part, dest)
+}
+
+func (m *Manager) finishDownload(d download, part, dest string) error {
+	if err := os.Rename(part, dest); err != nil {
+		return err
+	}
+
+	var err error
+	m.sync(func() {
+		// the item may have been refreshed with new enclosures meanwhile
+		if e := d.item.FindEnclosure(d.enclosure.URL); e != nil {
+			e.Path = dest
+		}
+		d.enclosure.Path = dest
+		if f := m.FindFeed(d.item.Belong); f != nil {
+			err = m.SaveFeed(f)
+		}
+	})
+	return err
+}
+
+// downloadDest returns where the enclosure is saved: a file named after its URL
+// in the directory named after its feed.
+// A hash of the URL tells apart the enclosures whose URLs end in the same name,
+// so that they do not share a file or a partial download.
+func (m *Manager) downloadDest(d download) string {
+	dir := m.DownloadPath
+	if dir == "" {
+		dir = filepath.Join(cache.DataPath, "downloads")
+	}
+
+	feedDir := d.item.Belong
+	if f := m.FindFeed(d.item.Belong); f != nil {
+		feedDir = f.Title
+	}
+
+	hash := fmt.Sprintf("%x", md5.Sum([]byte(d.enclosure.URL)))
+	name := ""
+	if u, err := url.Parse(d.enclosure.URL); err == nil {
+		name = path.Base(u.Path)
+	}
+	if name == "" || name == "." || name == "/" {
+		name = hash
+	} else {
+		ext := path.Ext(name)
+		name = strings.TrimSuffix(name, ext) + "-" + hash[:8] + ext
+	}
+	return filepath.Join(dir, sanitizeFileName(feedDir), sanitizeFileName(name))
+}
+
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < ' ' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// progressReader reports how much has been read at most once in progressInterval.
+type progressReader struct {
+	reader   io.Reader
+	done     int64
+	reported time.Time
+	report   func(done int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.done += int64(n)
+	if now := time.Now(); now.Sub(r.reported) >= progressInterval || err == io.EOF {
+		r.reported = now
+		r.report(r.done)
+	}
+	return n, err
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	fd "github.com/apxxxxxxe/rfcui/feed"
+)
+
+type EventType int
+
+const (
+	// FeedsChanged is emitted when feeds are added, removed or edited.
+	FeedsChanged EventType = iota
+	// GroupsChanged is emitted when groups are added, removed or edited.
+	GroupsChanged
+	// FeedUpdated is emitted each time a feed is refreshed during an update.
+	FeedUpdated
+	// UpdateFinished is emitted when all feeds and groups are refreshed.
+	UpdateFinished
+	// DownloadProgress is emitted from time to time while an enclosure is downloaded.
+	DownloadProgress
+	// DownloadFinished is emitted when an enclosure is downloaded or failed to be.
+	DownloadFinished
+)
+
+type Event struct {
+	Type EventType
+	Feed *fd.Feed
+	Item *fd.Item
+	Err  error
+
+	// progress of the update in progress, or bytes of the download in progress
+	Done   int
+	Total  int
+	Failed int
+}
+
+// Subscribe registers handler to be called on every event.
+// Handlers are called on the goroutine which caused the event.
+func (m *Manager) Subscribe(handler func(Event)) {
+	m.handlers = append(m.handlers, handler)
+}
+
+func (m *Manager) emit(e Event) {
+	for _, handler := range m.handlers {
+		handler(e)
+	}
+}
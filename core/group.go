@@ -0,0 +1,233 @@
+package core
+
+import (
+	"sort"
+	"strings"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/group"
+	"github.com/apxxxxxxe/rfcui/store"
+
+	"github.com/pkg/errors"
+)
+
+var ErrEmptyGroupName = errors.New("the name of a group must not be empty")
+
+func (m *Manager) SaveGroup(g *group.Group) error {
+	return m.Store.Update(func(tx store.Tx) error {
+		return tx.PutGroup(g)
+	})
+}
+
+// ResolveGroups makes Groups again from the groups, the saved searches and the starred items.
+// The items of a group are taken from its member feeds as they are now.
+func (m *Manager) ResolveGroups() {
+	groups := []*fd.Feed{}
+	views := map[*fd.Feed]*group.Group{}
+	for _, g := range m.UserGroups {
+		view := &fd.Feed{
+			Title:       g.Name,
+			Color:       g.Options.Color,
+			Description: "",
+			Link:        "",
+			FeedLinks:   append([]string{}, g.Members...),
+			Items:       g.Items(m.Feeds),
+		}
+		views[view] = g
+		groups = append(groups, view)
+	}
+	groups = append(groups, m.savedSearchGroups()...)
+	groups = append(groups, m.Starred)
+
+	m.Groups = groups
+	m.views = views
+}
+
+// GroupItems returns the items of the group shown as view, taken from its member feeds now.
+func (m *Manager) GroupItems(view *fd.Feed) []*fd.Item {
+	if g := m.views[view]; g != nil {
+		return g.Items(m.Feeds)
+	}
+	return view.Items
+}
+
+// FindGroup returns the group of the name as it is shown in Groups.
+func (m *Manager) FindGroup(name string) *fd.Feed {
+	for _, view := range m.Groups {
+		if m.views[view] != nil && view.Title == name {
+			return view
+		}
+	}
+	return nil
+}
+
+func (m *Manager) findUserGroup(name string) *group.Group {
+	for _, g := range m.UserGroups {
+		if g.Name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+// AddGroup makes a group of the feeds, or adds them to the group of the same name.
+func (m *Manager) AddGroup(name string, feedLinks []string) (*fd.Feed, error) {
+	if name == "" {
+		return nil, ErrEmptyGroupName
+	}
+	if m.FindSavedSearch(name) != nil || name == StarredTitle {
+		return nil, errors.Wrap(ErrDuplicateTitle, name)
+	}
+	g := m.findUserGroup(name)
+	if g != nil {
+		g.AddMembers(feedLinks)
+	} else {
+		g = group.New(name, feedLinks, m.nextGroupOrder())
+		m.UserGroups = append(m.UserGroups, g)
+	}
+
+	if err := m.SaveGroup(g); err != nil {
+		return nil, err
+	}
+	m.ResolveGroups()
+	view := m.FindGroup(name)
+	m.emit(Event{Type: GroupsChanged, Feed: view})
+	return view, nil
+}
+
+func (m *Manager) nextGroupOrder() int {
+	order := 0
+	for _, g := range m.UserGroups {
+		if g.Order >= order {
+			order = g.Order + 1
+		}
+	}
+	return order
+}
+
+func (m *Manager) DeleteGroup(view *fd.Feed) error {
+	if view == m.Starred {
+		return errors.Wrap(ErrPermanentGroup, view.Title)
+	}
+	deleted, err := m.deleteSavedSearch(view.Title)
+	if err != nil {
+		return err
+	}
+	if g := m.views[view]; !deleted && g != nil {
+		err := m.Store.Update(func(tx store.Tx) error {
+			return tx.DeleteGroup(g.ID)
+		})
+		if err != nil {
+			return errors.Wrap(ErrRmFailed, err.Error())
+		}
+		for i, userGroup := range m.UserGroups {
+			if userGroup == g {
+				m.UserGroups = append(m.UserGroups[:i], m.UserGroups[i+1:]...)
+				break
+			}
+		}
+	}
+	m.ResolveGroups()
+	m.emit(Event{Type: GroupsChanged})
+	return nil
+}
+
+// RenameGroup renames the group or the saved search shown as view.
+func (m *Manager) RenameGroup(view *fd.Feed, name string) error {
+	if view == m.Starred {
+		return errors.Wrap(ErrPermanentGroup, view.Title)
+	}
+	if name == "" {
+		return ErrEmptyGroupName
+	}
+	if name == view.Title {
+		return nil
+	}
+	for _, other := range m.Groups {
+		if other.Title == name {
+			return errors.Wrap(ErrDuplicateTitle, name)
+		}
+	}
+
+	if s := m.FindSavedSearch(view.Title); s != nil {
+		s.Title = name
+		if err := m.SaveSavedSearches(); err != nil {
+			s.Title = view.Title
+			return err
+		}
+	} else if g := m.views[view]; g != nil {
+		g.Name = name
+		if err := m.SaveGroup(g); err != nil {
+			g.Name = view.Title
+			return err
+		}
+	}
+	m.ResolveGroups()
+	m.emit(Event{Type: GroupsChanged})
+	return nil
+}
+
+// MoveGroup moves the group shown as view by delta places among the groups.
+// It reports whether the group has moved.
+func (m *Manager) MoveGroup(view *fd.Feed, delta int) (bool, error) {
+	g := m.views[view]
+	if g == nil {
+		return false, nil
+	}
+	group.Sort(m.UserGroups)
+	from := -1
+	for i, userGroup := range m.UserGroups {
+		if userGroup == g {
+			from = i
+		}
+	}
+	to := from + delta
+	if to < 0 || to >= len(m.UserGroups) {
+		return false, nil
+	}
+
+	m.UserGroups = append(m.UserGroups[:from], m.UserGroups[from+1:]...)
+	m.UserGroups = append(m.UserGroups[:to], append([]*group.Group{g}, m.UserGroups[to:]...)...)
+	err := m.Store.Update(func(tx store.Tx) error {
+		for i, userGroup := range m.UserGroups {
+			userGroup.Order = i
+			if err := tx.PutGroup(userGroup); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	m.ResolveGroups()
+	m.emit(Event{Type: GroupsChanged})
+	return true, nil
+}
+
+// removeMember takes the feed out of every group.
+func (m *Manager) removeMember(tx store.Tx, feedLink string) error {
+	for _, g := range m.UserGroups {
+		if g.HasMember(feedLink) {
+			g.RemoveMember(feedLink)
+			if err := tx.PutGroup(g); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SortGroups puts the groups in their order, then the saved searches and the starred items by title.
+func (m *Manager) SortGroups() {
+	sort.SliceStable(m.Groups, func(i, j int) bool {
+		gi, gj := m.views[m.Groups[i]], m.views[m.Groups[j]]
+		switch {
+		case gi != nil && gj != nil:
+			return gi.Order < gj.Order
+		case gi != nil || gj != nil:
+			return gi != nil
+		}
+		return strings.Compare(m.Groups[i].Title, m.Groups[j].Title) == -1
+	})
+}
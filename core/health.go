@@ -0,0 +1,48 @@
+package core
+
+import (
+	"time"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+)
+
+const (
+	minRetryDelay = time.Minute
+	maxRetryDelay = 24 * time.Hour
+)
+
+// retryDelay returns how long to wait before retrying a feed that failed failures times in a row.
+func retryDelay(failures int) time.Duration {
+	delay := minRetryDelay
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
+
+func (m *Manager) recordSuccess(f *fd.Feed, now time.Time) {
+	f.Status.LastSuccess = now
+	f.Status.LastError = ""
+	f.Status.Failures = 0
+	f.Status.NextRetry = time.Time{}
+}
+
+func (m *Manager) recordFailure(f *fd.Feed, now time.Time, err error) {
+	f.Status.LastError = err.Error()
+	f.Status.Failures++
+	f.Status.NextRetry = now.Add(retryDelay(f.Status.Failures))
+}
+
+// BrokenFeeds returns the feeds whose last refresh failed.
+func (m *Manager) BrokenFeeds() []*fd.Feed {
+	result := []*fd.Feed{}
+	for _, f := range m.Feeds {
+		if f.Status.IsBroken() {
+			result = append(result, f)
+		}
+	}
+	return result
+}
@@ -0,0 +1,485 @@
+package core
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apxxxxxxe/rfcui/cache"
+	mycolor "github.com/apxxxxxxe/rfcui/color"
+	"github.com/apxxxxxxe/rfcui/config"
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/filter"
+	"github.com/apxxxxxxe/rfcui/group"
+	"github.com/apxxxxxxe/rfcui/index"
+	myio "github.com/apxxxxxxe/rfcui/io"
+	"github.com/apxxxxxxe/rfcui/store"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	TodaysFeedTitle = "Today's Items"
+	importedKey     = "imported_from_files"
+)
+
+var ErrRmFailed = errors.New("faled to remove files or dirs")
+
+// Manager owns the subscribed feeds and groups, and keeps them in sync with the store.
+type Manager struct {
+	Feeds []*fd.Feed
+	// the groups, the saved searches and the starred items as they are shown, made by ResolveGroups
+	Groups []*fd.Feed
+	// the groups the user has made
+	UserGroups []*group.Group
+	// where the feeds and groups are kept, opened by Load unless set
+	Store store.Store
+	// records found broken and put into quarantine by Load
+	Corruptions []*store.Corruption
+
+	// limits of simultaneous fetches in an update, in total and per host
+	Concurrency  int
+	PerHostLimit int
+	// interval of background refreshes of feeds without their own, or 0 to disable them
+	RefreshInterval time.Duration
+	// where enclosures are downloaded to
+	DownloadPath string
+	// rules applied to the items of every refreshed feed
+	Filters []*filter.Rule
+	// how long the items which have left their feeds are kept
+	Archive config.Archive
+	// full-text index of the items of the feeds, archived ones included, loaded by Load
+	Index *index.Index
+	// runs fn, which changes the feeds and groups, for the updates and downloads in the background.
+	// A UI reading them on its own goroutine sets it to run fn there and wait for it;
+	// by default fn runs under a lock of the manager.
+	Sync func(fn func())
+	// queries shown as groups, loaded by Load
+	SavedSearches []*SavedSearch
+	// the group of the starred items, loaded by Load
+	Starred *fd.Feed
+
+	starredFeedTitles map[string]string
+	// the groups shown in Groups
+	views map[*fd.Feed]*group.Group
+	// set by LoadReadOnly
+	readOnly bool
+
+	handlers []func(Event)
+	mu       sync.Mutex
+	updateMu sync.Mutex
+	updating bool
+
+	downloadMu  sync.Mutex
+	downloads   []download
+	queued      map[string]bool
+	downloading bool
+}
+
+// NewManagerWithConfig returns a Manager whose refreshes are set up by conf.
+func NewManagerWithConfig(conf *config.Config) *Manager {
+	m := NewManager()
+	m.Concurrency = conf.Refresh.Concurrency
+	m.PerHostLimit = conf.Refresh.PerHost
+	m.RefreshInterval = conf.Refresh.Interval
+	m.DownloadPath = conf.DownloadPath
+	m.Filters = conf.Filters
+	m.Archive = conf.Archive
+	return m
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		Feeds:           []*fd.Feed{},
+		Groups:          []*fd.Feed{},
+		UserGroups:      []*group.Group{},
+		Starred:         newStarredGroup(),
+		Concurrency:     defaultConcurrency,
+		PerHostLimit:    defaultPerHostLimit,
+		RefreshInterval: defaultRefreshInterval,
+		Archive:         config.Default().Archive,
+		handlers:        []func(Event){},
+		downloads:       []download{},
+		queued:          map[string]bool{},
+
+		starredFeedTitles: map[string]string{},
+		views:             map[*fd.Feed]*group.Group{},
+	}
+}
+
+func (m *Manager) Load() error {
+	if m.Store == nil {
+		s, err := store.Open(filepath.Join(cache.DataPath, store.FileName))
+		if err != nil {
+			return err
+		}
+		m.Store = s
+	}
+	_, corruptions, err := store.MigrateCache(m.Store, cache.CachePath)
+	if err != nil {
+		return err
+	}
+	m.Corruptions = append(m.Corruptions, corruptions...)
+	if err := m.importFiles(); err != nil {
+		return err
+	}
+
+	// the records are checked only when one could not be read, or the schema has just been migrated
+	data, err := readStore(m.Store)
+	if errors.Is(err, store.ErrBrokenRecord) || (err == nil && m.Store.Migrated()) {
+		corruptions, err = m.Store.Check()
+		if err != nil {
+			return err
+		}
+		m.Corruptions = append(m.Corruptions, corruptions...)
+		if data == nil || len(corruptions) > 0 {
+			data, err = readStore(m.Store)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return m.loadStored(data)
+}
+
+// LoadReadOnly loads the feeds and groups as Load does, without writing anything to the store:
+// the cache and the files are not migrated, the broken records are not put into quarantine
+// and the changes of the search index are not stored. The store cannot be changed afterwards.
+func (m *Manager) LoadReadOnly() error {
+	m.readOnly = true
+	if m.Store == nil {
+		s, err := store.OpenReadOnly(filepath.Join(cache.DataPath, store.FileName))
+		if os.IsNotExist(err) {
+			// nothing is stored yet
+			return m.loadStored(&stored{searches: savedSearchRecords(defaultSavedSearches())})
+		}
+		if err != nil {
+			return err
+		}
+		m.Store = s
+	}
+	data, err := readStore(m.Store)
+	if err != nil {
+		return err
+	}
+	return m.loadStored(data)
+}
+
+func (m *Manager) loadStored(data *stored) error {
+	// the filters may have changed since the feeds were stored
+	for _, f := range data.feeds {
+		filter.Apply(m.Filters, f, filter.Seen(f))
+	}
+	m.Feeds = append(m.Feeds, data.feeds...)
+	m.UserGroups = append(m.UserGroups, data.groups...)
+	if err := m.loadIndex(data.docs); err != nil {
+		return err
+	}
+	if err := m.loadSavedSearches(data.searches); err != nil {
+		return err
+	}
+	m.loadStarred(data.starred)
+	m.ResolveGroups()
+	m.emit(Event{Type: FeedsChanged})
+	m.emit(Event{Type: GroupsChanged})
+	return nil
+}
+
+// stored is what is kept in the store.
+type stored struct {
+	feeds    []*fd.Feed
+	groups   []*group.Group
+	starred  []*store.StarredItem
+	searches []*store.SavedSearch
+	docs     []*index.Doc
+}
+
+func readStore(s store.Store) (*stored, error) {
+	var (
+		data = &stored{}
+		err  error
+	)
+	if data.feeds, data.groups, err = s.Load(); err != nil {
+		return nil, err
+	}
+	if data.starred, err = s.LoadStarred(); err != nil {
+		return nil, err
+	}
+	if data.searches, err = s.LoadSavedSearches(); err != nil {
+		return nil, err
+	}
+	if data.docs, err = s.LoadIndex(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// importFiles copies the starred items and the saved searches, which were kept in files of their own, into the store, once.
+// The files are left as they are.
+func (m *Manager) importFiles() error {
+	corruptions := []*store.Corruption{}
+	err := m.Store.Update(func(tx store.Tx) error {
+		if tx.Meta(importedKey) != nil {
+			return nil
+		}
+		for _, importFile := range []func(store.Tx) (*store.Corruption, error){importStarred, importSavedSearches} {
+			c, err := importFile(tx)
+			if err != nil {
+				return err
+			}
+			if c != nil {
+				corruptions = append(corruptions, c)
+			}
+		}
+		return tx.PutMeta(importedKey, []byte(time.Now().Format(time.RFC3339)))
+	})
+	if err != nil {
+		return err
+	}
+	m.Corruptions = append(m.Corruptions, corruptions...)
+	return nil
+}
+
+// Close closes the store.
+func (m *Manager) Close() error {
+	if m.Store == nil {
+		return nil
+	}
+	return m.Store.Close()
+}
+
+// SaveFeed stores f, with the changes of the search index.
+func (m *Manager) SaveFeed(f *fd.Feed) error {
+	return m.updateWithIndex(func(tx store.Tx) error {
+		return tx.PutFeed(f)
+	})
+}
+
+func (m *Manager) FindFeed(feedLink string) *fd.Feed {
+	for _, f := range m.Feeds {
+		if link, err := f.GetFeedLink(); err == nil && link == feedLink {
+			return f
+		}
+	}
+	return nil
+}
+
+// AddFeed fetches the feed at url and subscribes to it.
+// If it is subscribed to already, the fetched feed is merged into the existing one as on an update.
+func (m *Manager) AddFeed(url string) (*fd.Feed, error) {
+	f, err := fd.GetFeedFromURL(url, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if existing := m.FindFeed(url); existing != nil {
+		if err := m.applyFetched(existing, f, nil); err != nil {
+			return nil, err
+		}
+		m.emit(Event{Type: FeedsChanged, Feed: existing})
+		return existing, nil
+	}
+
+	m.Feeds = append(m.Feeds, f)
+	filter.Apply(m.Filters, f, map[string]bool{})
+	m.prune(f, time.Now())
+	if m.Index != nil {
+		m.Index.Add(f)
+	}
+
+	if err := m.SaveFeed(f); err != nil {
+		return nil, err
+	}
+	m.emit(Event{Type: FeedsChanged, Feed: f})
+	return f, nil
+}
+
+// AddPlaceholderFeed subscribes to url without fetching it.
+// The feed is filled on the next update.
+// If title is not empty, it is kept instead of the title of the fetched feed.
+func (m *Manager) AddPlaceholderFeed(url, title string, color int) *fd.Feed {
+	if f := m.FindFeed(url); f != nil {
+		return f
+	}
+
+	f := &fd.Feed{
+		Title:       "getting " + url + "...",
+		Color:       -1,
+		Description: "update to get details",
+		Link:        "",
+		FeedLinks:   []string{url},
+		Items:       []*fd.Item{},
+	}
+	if title != "" {
+		// a valid color keeps Refresh from overwriting the title
+		f.Title = title
+		if isValidColor(color) {
+			f.Color = color
+		} else {
+			f.Color = randomColor()
+		}
+	}
+	m.Feeds = append(m.Feeds, f)
+	m.emit(Event{Type: FeedsChanged, Feed: f})
+	return f
+}
+
+func (m *Manager) DeleteFeed(f *fd.Feed) error {
+	feedLink, err := f.GetFeedLink()
+	if err != nil {
+		return err
+	}
+	if m.Index != nil {
+		m.Index.RemoveFeed(feedLink)
+	}
+	err = m.updateWithIndex(func(tx store.Tx) error {
+		if err := tx.DeleteFeed(feedLink); err != nil {
+			return err
+		}
+		return m.removeMember(tx, feedLink)
+	})
+	if err != nil {
+		if m.Index != nil {
+			m.Index.Add(f)
+		}
+		return errors.Wrap(ErrRmFailed, err.Error())
+	}
+	for i, feed := range m.Feeds {
+		if feed == f {
+			m.Feeds = append(m.Feeds[:i], m.Feeds[i+1:]...)
+			break
+		}
+	}
+	m.ResolveGroups()
+	m.emit(Event{Type: FeedsChanged})
+	m.emit(Event{Type: GroupsChanged})
+	return nil
+}
+
+func (m *Manager) RenameFeed(f *fd.Feed, title string) error {
+	oldTitle := f.Title
+	f.Title = title
+	if err := m.SaveFeed(f); err != nil {
+		f.Title = oldTitle
+		return err
+	}
+	m.emit(Event{Type: FeedsChanged, Feed: f})
+	return nil
+}
+
+// ResetTitle sets the title of the feed back to the one the feed provides.
+func (m *Manager) ResetTitle(f *fd.Feed) error {
+	feedLink, err := f.GetFeedLink()
+	if err != nil {
+		return err
+	}
+	fetched, err := fd.GetFeedFromURL(feedLink, "")
+	if err != nil {
+		return err
+	}
+	return m.RenameFeed(f, fetched.Title)
+}
+
+func (m *Manager) SetColor(f *fd.Feed, color int) error {
+	f.Color = color
+	for _, item := range f.Items {
+		item.Color = color
+	}
+	if err := m.SaveFeed(f); err != nil {
+		return err
+	}
+	m.emit(Event{Type: FeedsChanged, Feed: f})
+	return nil
+}
+
+func (m *Manager) SetRandomColor(f *fd.Feed) error {
+	return m.SetColor(f, randomColor())
+}
+
+// MarkRead marks the item as read and saves its state.
+func (m *Manager) MarkRead(item *fd.Item) error {
+	item.Read = true
+	f := m.FindFeed(item.Belong)
+	if f == nil {
+		return nil
+	}
+	for _, i := range f.Items {
+		if i.ID == item.ID {
+			i.Read = true
+		}
+	}
+	err := m.Store.Update(func(tx store.Tx) error {
+		if err := tx.PutItemState(item); err != nil {
+			return err
+		}
+		if i := m.findStarred(item.Belong, item.ID); i >= 0 {
+			m.Starred.Items[i].Read = true
+			return m.putStarred(tx, m.Starred.Items[i])
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	m.emit(Event{Type: FeedsChanged, Feed: f})
+	m.emit(Event{Type: GroupsChanged})
+	return nil
+}
+
+func (m *Manager) SortFeeds() {
+	sortFeeds(m.Feeds)
+}
+
+func sortFeeds(feeds []*fd.Feed) {
+	sort.Slice(feeds, func(i, j int) bool {
+		return strings.Compare(feeds[i].Title, feeds[j].Title) == -1
+	})
+	sort.Slice(feeds, func(i, j int) bool {
+		return feeds[i].IsMerged() && !feeds[j].IsMerged()
+	})
+}
+
+// ImportList subscribes to the urls listed in the file at path, one per line.
+func (m *Manager) ImportList(path string) error {
+	if !myio.IsFile(path) {
+		return nil
+	}
+
+	_, feedURLs, err := myio.GetLines(path)
+	if err != nil {
+		return err
+	}
+
+	for _, url := range feedURLs {
+		m.AddPlaceholderFeed(url, "", -1)
+	}
+	return nil
+}
+
+// ExportList writes the urls of the feeds to the file at path, one per line.
+func (m *Manager) ExportList(path string) error {
+	listFile, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer listFile.Close()
+
+	for _, feed := range m.Feeds {
+		if _, err := listFile.WriteString(feed.FeedLinks[0] + "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isValidColor(color int) bool {
+	return color > 0 && color < len(mycolor.TcellColors)
+}
+
+func randomColor() int {
+	return mycolor.ComfortableColorCode[rand.Intn(len(mycolor.ComfortableColorCode))]
+}
@@ -0,0 +1,233 @@
+package core
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apxxxxxxe/rfcui/cache"
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/store"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestLoadImportsFiles(t *testing.T) {
+	dir := t.TempDir()
+	cache.DataPath = dir
+	cache.CachePath = filepath.Join(dir, "cache")
+
+	const feedLink = "https://example.com/feed"
+	buf := bytes.NewBuffer(nil)
+	file := starredFile{
+		Items:      []*fd.Item{{ID: "1", Belong: feedLink, Title: "Reference", Starred: true}},
+		FeedTitles: map[string]string{feedLink: "Blog"},
+	}
+	if err := gob.NewEncoder(buf).Encode(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(starredPath(), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	searches := "[[search]]\ntitle = \"Go\"\nquery = \"go\"\n"
+	if err := ioutil.WriteFile(savedSearchesPath(), []byte(searches), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewManager()
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+	// the files are read only once
+	for _, path := range []string{starredPath(), savedSearchesPath()} {
+		if err := ioutil.WriteFile(path, []byte("broken"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m = NewManager()
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if len(m.Corruptions) != 0 {
+		t.Errorf("got corruptions %v, want none", m.Corruptions)
+	}
+	if len(m.Starred.Items) != 1 || m.Starred.Items[0].Title != "Reference" || m.FeedTitle(feedLink) != "Blog" {
+		t.Errorf("got starred items %v, want the one in the file", m.Starred.Items)
+	}
+	if len(m.SavedSearches) != 1 || m.SavedSearches[0].Title != "Go" {
+		t.Errorf("got saved searches %v, want the one in the file", m.SavedSearches)
+	}
+}
+
+func TestLoadQuarantinesBrokenFiles(t *testing.T) {
+	dir := t.TempDir()
+	cache.DataPath = dir
+	cache.CachePath = filepath.Join(dir, "cache")
+	for _, path := range []string{starredPath(), savedSearchesPath()} {
+		if err := ioutil.WriteFile(path, []byte("broken"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := NewManager()
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := map[string]bool{}
+	for _, c := range m.Corruptions {
+		kinds[c.Kind] = true
+	}
+	if len(m.Corruptions) != 2 || !kinds[store.KindStarredFile] || !kinds[store.KindSearchFile] {
+		t.Errorf("got corruptions %v, want the starred and saved searches files", m.Corruptions)
+	}
+	if len(m.Starred.Items) != 0 {
+		t.Errorf("got %d starred items, want none", len(m.Starred.Items))
+	}
+	if len(m.SavedSearches) != 1 || m.SavedSearches[0].Title != TodaysFeedTitle {
+		t.Errorf("got saved searches %v, want the default ones", m.SavedSearches)
+	}
+
+	// the files are not read again
+	m.Close()
+	m = NewManager()
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if len(m.Corruptions) != 0 {
+		t.Errorf("got corruptions %v after loading again, want none", m.Corruptions)
+	}
+}
+
+func TestLoadQuarantinesBrokenRecords(t *testing.T) {
+	cache.DataPath = t.TempDir()
+	cache.CachePath = t.TempDir()
+
+	m := NewManager()
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	const feedLink = "https://example.com/feed"
+	f := &fd.Feed{Title: "Blog", FeedLinks: []string{feedLink}, Items: []*fd.Item{{ID: "1", Belong: feedLink, Title: "Reference"}}}
+	if err := m.SaveFeed(f); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	db, err := bolt.Open(filepath.Join(cache.DataPath, store.FileName), 0644, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("feeds")).Put([]byte(feedLink), []byte("broken"))
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m = NewManager()
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if len(m.Corruptions) != 1 || m.Corruptions[0].Kind != store.KindFeed {
+		t.Errorf("got corruptions %v, want the feed", m.Corruptions)
+	}
+	if len(m.Feeds) != 0 {
+		t.Errorf("got %d feeds, want none", len(m.Feeds))
+	}
+}
+
+func TestAddFeedMergesIntoTheSubscribedFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, testRSS, "blog")
+	}))
+	defer server.Close()
+	cache.DataPath = t.TempDir()
+	cache.CachePath = t.TempDir()
+
+	m := NewManager()
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	f, err := m.AddFeed(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Items[0].Read = true
+
+	again, err := m.AddFeed(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != f || len(m.Feeds) != 1 {
+		t.Fatalf("got %d feeds, want the subscribed feed only", len(m.Feeds))
+	}
+	if len(f.Items) != 2 || !f.Items[0].Read {
+		t.Errorf("got items %v, want the 2 items with the first read", f.Items)
+	}
+}
+
+func TestLoadReadOnlyWritesNothing(t *testing.T) {
+	cache.DataPath = t.TempDir()
+	cache.CachePath = t.TempDir()
+	path := filepath.Join(cache.DataPath, store.FileName)
+
+	m := NewManager()
+	if err := m.LoadReadOnly(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("the database was made by LoadReadOnly: %v", err)
+	}
+	if len(m.SavedSearches) != 1 || m.SavedSearches[0].Title != TodaysFeedTitle {
+		t.Errorf("got saved searches %v, want the default ones", m.SavedSearches)
+	}
+
+	m = NewManager()
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	const feedLink = "https://example.com/feed"
+	f := &fd.Feed{Title: "Blog", FeedLinks: []string{feedLink}, Items: []*fd.Item{{ID: "1", Belong: feedLink, Title: "Reference"}}}
+	if err := m.Store.Update(func(tx store.Tx) error { return tx.PutFeed(f) }); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+	before, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the feed is not indexed yet
+	m = NewManager()
+	if err := m.LoadReadOnly(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UpdateGroups(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Feeds) != 1 || len(m.Search("reference")) != 1 {
+		t.Errorf("got %d feeds and %d results, want the stored feed", len(m.Feeds), len(m.Search("reference")))
+	}
+	m.Close()
+	after, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Errorf("the database was written by LoadReadOnly")
+	}
+}
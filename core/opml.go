@@ -0,0 +1,65 @@
+package core
+
+import (
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	myio "github.com/apxxxxxxe/rfcui/io"
+	"github.com/apxxxxxxe/rfcui/opml"
+)
+
+// ExportOPML writes the feeds to path, with the groups as folders of their member feeds.
+func (m *Manager) ExportOPML(path string) error {
+	return m.OPML().Save(path)
+}
+
+func (m *Manager) OPML() *opml.OPML {
+	groups := []*fd.Feed{}
+	for _, g := range m.Groups {
+		if m.views[g] != nil {
+			groups = append(groups, g)
+		}
+	}
+	return opml.FromFeeds(m.Feeds, groups)
+}
+
+// ImportOPML subscribes to the feeds in path which are not subscribed yet,
+// and makes a group from each folder of the document.
+// A folder is merged into the group of the same name, so importing a document again changes nothing.
+func (m *Manager) ImportOPML(path string) error {
+	doc, err := opml.Load(path)
+	if err != nil {
+		return err
+	}
+
+	for _, outline := range doc.Body.Outlines {
+		if err := m.importOutline(outline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importOutline puts each feed into the group of the folder nearest to it only.
+// As groups cannot be nested, the folders in a folder make groups of their own.
+func (m *Manager) importOutline(outline *opml.Outline) error {
+	if outline.IsFeed() {
+		m.AddPlaceholderFeed(outline.XMLURL, outline.GetTitle(), outline.Color)
+		return nil
+	}
+
+	feedLinks := []string{}
+	for _, child := range outline.Outlines {
+		if err := m.importOutline(child); err != nil {
+			return err
+		}
+		if child.IsFeed() {
+			feedLinks = append(feedLinks, child.XMLURL)
+		}
+	}
+	feedLinks = myio.RemoveDuplicate(feedLinks)
+
+	if len(feedLinks) == 0 {
+		return nil
+	}
+	_, err := m.AddGroup(outline.GetTitle(), feedLinks)
+	return err
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/apxxxxxxe/rfcui/cache"
+)
+
+const nestedOPML = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0"><head><title>feeds</title></head><body>
+<outline text="Tech">
+  <outline text="Blog" type="rss" xmlUrl="https://example.com/blog"/>
+  <outline text="Go">
+    <outline text="Go Blog" type="rss" xmlUrl="https://go.dev/blog/feed.atom"/>
+  </outline>
+</outline>
+<outline text="News" type="rss" xmlUrl="https://example.com/news"/>
+</body></opml>`
+
+func TestImportNestedOPML(t *testing.T) {
+	cache.DataPath = t.TempDir()
+	cache.CachePath = t.TempDir()
+	path := filepath.Join(t.TempDir(), "feeds.opml")
+	if err := ioutil.WriteFile(path, []byte(nestedOPML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewManager()
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	// importing again changes nothing
+	for i := 0; i < 2; i++ {
+		if err := m.ImportOPML(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(m.Feeds) != 3 {
+		t.Errorf("got %d feeds, want 3", len(m.Feeds))
+	}
+	want := map[string][]string{
+		"Tech": {"https://example.com/blog"},
+		"Go":   {"https://go.dev/blog/feed.atom"},
+	}
+	got := map[string][]string{}
+	for _, g := range m.UserGroups {
+		got[g.Name] = g.Members
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got groups %v, want %v", got, want)
+	}
+}
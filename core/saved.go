@@ -0,0 +1,185 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/apxxxxxxe/rfcui/cache"
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/query"
+	"github.com/apxxxxxxe/rfcui/store"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+)
+
+const savedSearchesFileName = "searches.toml"
+
+var ErrDuplicateTitle = errors.New("a group of the same title already exists")
+
+// SavedSearch is a virtual group of the items of all feeds matching a query.
+// Its items are collected again after every refresh.
+type SavedSearch struct {
+	Title string `toml:"title"`
+	Query string `toml:"query"`
+
+	query *query.Query
+}
+
+// savedSearchFile is what the saved searches were saved as before they were kept in the store.
+type savedSearchFile struct {
+	Searches []*SavedSearch `toml:"search"`
+}
+
+func newSavedSearch(title, text string) (*SavedSearch, error) {
+	q, err := query.Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, title)
+	}
+	return &SavedSearch{Title: title, Query: text, query: q}, nil
+}
+
+func defaultSavedSearches() []*SavedSearch {
+	today, _ := newSavedSearch(TodaysFeedTitle, "since:today")
+	return []*SavedSearch{today}
+}
+
+func savedSearchesPath() string {
+	return filepath.Join(cache.DataPath, savedSearchesFileName)
+}
+
+func (m *Manager) loadSavedSearches(records []*store.SavedSearch) error {
+	m.SavedSearches = []*SavedSearch{}
+	for _, r := range records {
+		s, err := newSavedSearch(r.Title, r.Query)
+		if err != nil {
+			return err
+		}
+		m.SavedSearches = append(m.SavedSearches, s)
+	}
+	return nil
+}
+
+// importSavedSearches copies the saved searches from the file they were saved in into the store,
+// or stores the default ones if there is no file.
+// A file which cannot be read is put into quarantine, and the default searches are stored instead.
+func importSavedSearches(tx store.Tx) (*store.Corruption, error) {
+	path := savedSearchesPath()
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, putSavedSearches(tx, defaultSavedSearches())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	searches, err := parseSavedSearchFile(b)
+	if err != nil {
+		if err := tx.Quarantine(store.KindSearchFile, path, b); err != nil {
+			return nil, err
+		}
+		return &store.Corruption{Kind: store.KindSearchFile, Name: path, Err: err}, putSavedSearches(tx, defaultSavedSearches())
+	}
+	return nil, putSavedSearches(tx, searches)
+}
+
+func parseSavedSearchFile(b []byte) ([]*SavedSearch, error) {
+	var file savedSearchFile
+	if _, err := toml.Decode(string(b), &file); err != nil {
+		return nil, err
+	}
+	searches := []*SavedSearch{}
+	for _, s := range file.Searches {
+		saved, err := newSavedSearch(s.Title, s.Query)
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, saved)
+	}
+	return searches, nil
+}
+
+func putSavedSearches(tx store.Tx, searches []*SavedSearch) error {
+	return tx.PutSavedSearches(savedSearchRecords(searches))
+}
+
+func savedSearchRecords(searches []*SavedSearch) []*store.SavedSearch {
+	records := []*store.SavedSearch{}
+	for _, s := range searches {
+		records = append(records, &store.SavedSearch{Title: s.Title, Query: s.Query})
+	}
+	return records
+}
+
+func (m *Manager) SaveSavedSearches() error {
+	return m.Store.Update(func(tx store.Tx) error {
+		return putSavedSearches(tx, m.SavedSearches)
+	})
+}
+
+func (m *Manager) FindSavedSearch(title string) *SavedSearch {
+	for _, s := range m.SavedSearches {
+		if s.Title == title {
+			return s
+		}
+	}
+	return nil
+}
+
+// AddSavedSearch saves text as a query and shows the items matching it as a group.
+func (m *Manager) AddSavedSearch(title, text string) (*SavedSearch, error) {
+	for _, g := range m.Groups {
+		if g.Title == title {
+			return nil, errors.Wrap(ErrDuplicateTitle, title)
+		}
+	}
+	s, err := newSavedSearch(title, text)
+	if err != nil {
+		return nil, err
+	}
+
+	m.SavedSearches = append(m.SavedSearches, s)
+	if err := m.SaveSavedSearches(); err != nil {
+		return nil, err
+	}
+	m.ResolveGroups()
+	m.emit(Event{Type: GroupsChanged})
+	return s, nil
+}
+
+// deleteSavedSearch forgets the saved search of the title, reporting whether there was one.
+func (m *Manager) deleteSavedSearch(title string) (bool, error) {
+	for i, s := range m.SavedSearches {
+		if s.Title == title {
+			m.SavedSearches = append(m.SavedSearches[:i], m.SavedSearches[i+1:]...)
+			return true, m.SaveSavedSearches()
+		}
+	}
+	return false, nil
+}
+
+// savedSearchGroups collects the items of each saved search into a group.
+func (m *Manager) savedSearchGroups() []*fd.Feed {
+	titles := map[string]string{}
+	for _, f := range m.Feeds {
+		feedLink, _ := f.GetFeedLink()
+		titles[feedLink] = f.Title
+	}
+
+	groups := []*fd.Feed{}
+	for _, s := range m.SavedSearches {
+		g, _ := fd.MergeFeeds(m.Feeds, s.Title)
+		g.Description = "Saved search: " + s.Query
+
+		items := []*fd.Item{}
+		for _, item := range g.Items {
+			if s.query.Match(item, titles[item.Belong]) {
+				items = append(items, item)
+			}
+		}
+		g.Items = items
+		groups = append(groups, g)
+	}
+	return groups
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"time"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+)
+
+const (
+	defaultRefreshInterval = 30 * time.Minute
+	schedulerTick          = time.Minute
+)
+
+// Interval returns how often f is refreshed in the background:
+// its own interval if set, otherwise the default one,
+// stretched to the update period the feed announces.
+func (m *Manager) Interval(f *fd.Feed) time.Duration {
+	if f.RefreshInterval > 0 {
+		return f.RefreshInterval
+	}
+	if m.RefreshInterval <= 0 {
+		return 0
+	}
+	if f.UpdateHint > m.RefreshInterval {
+		return f.UpdateHint
+	}
+	return m.RefreshInterval
+}
+
+func (m *Manager) SetRefreshInterval(f *fd.Feed, interval time.Duration) error {
+	f.RefreshInterval = interval
+	if err := m.SaveFeed(f); err != nil {
+		return err
+	}
+	m.emit(Event{Type: FeedsChanged, Feed: f})
+	return nil
+}
+
+// RunScheduler refreshes every feed whose interval has passed since it was last checked,
+// until stop is closed. A failing feed is retried with exponential backoff instead.
+func (m *Manager) RunScheduler(stop <-chan struct{}) {
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := m.refreshDueFeeds(); err != nil && err != ErrUpdateInProgress {
+				m.emit(Event{Type: UpdateFinished, Err: err})
+			}
+		}
+	}
+}
+
+func (m *Manager) refreshDueFeeds() error {
+	now := time.Now()
+	due := []*fd.Feed{}
+	m.sync(func() {
+		for _, f := range m.Feeds {
+			if m.isDue(f, now) {
+				due = append(due, f)
+			}
+		}
+	})
+	if len(due) == 0 {
+		return nil
+	}
+
+	if !m.beginUpdate() {
+		return ErrUpdateInProgress
+	}
+	defer m.endUpdate()
+
+	failed := m.refreshFeeds(due)
+
+	var err error
+	m.sync(func() {
+		err = m.UpdateGroups()
+	})
+	if err != nil {
+		return err
+	}
+
+	m.emit(Event{Type: UpdateFinished, Done: len(due), Total: len(due), Failed: failed})
+	return nil
+}
+
+func (m *Manager) isDue(f *fd.Feed, now time.Time) bool {
+	interval := m.Interval(f)
+	if interval <= 0 {
+		return false
+	}
+	if f.Status.IsBroken() {
+		return !now.Before(f.Status.NextRetry)
+	}
+	return now.Sub(f.Status.LastChecked) >= interval
+}
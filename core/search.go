@@ -0,0 +1,101 @@
+package core
+
+import (
+	"time"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/index"
+	"github.com/apxxxxxxe/rfcui/store"
+)
+
+// loadIndex makes the search index of the stored docs.
+// The index then catches up with the feeds, so that it is built from them the first time
+// and a doc put into quarantine is indexed again.
+func (m *Manager) loadIndex(docs []*index.Doc) error {
+	m.Index = index.FromDocs(docs)
+
+	feedLinks := map[string]bool{}
+	for _, f := range m.Feeds {
+		feedLink, _ := f.GetFeedLink()
+		feedLinks[feedLink] = true
+		m.Index.Add(f)
+	}
+	for _, feedLink := range m.Index.Feeds() {
+		if !feedLinks[feedLink] {
+			m.Index.RemoveFeed(feedLink)
+		}
+	}
+	if changed, removed := m.Index.Changes(); m.readOnly || len(changed) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return m.updateWithIndex(func(tx store.Tx) error { return nil })
+}
+
+// updateWithIndex runs fn in a transaction of the store, and stores the changes of the index in it.
+func (m *Manager) updateWithIndex(fn func(tx store.Tx) error) error {
+	if m.Index == nil {
+		return m.Store.Update(fn)
+	}
+	changed, removed := m.Index.Changes()
+	err := m.Store.Update(func(tx store.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		for _, doc := range changed {
+			if err := tx.PutIndexDoc(doc); err != nil {
+				return err
+			}
+		}
+		for _, doc := range removed {
+			if err := tx.DeleteIndexDoc(doc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	m.Index.Stored()
+	return nil
+}
+
+// Search returns the items of all feeds matching text, archived ones included, best first.
+// Items which have left their feeds are returned as far as the index knows them.
+func (m *Manager) Search(text string) []*fd.Item {
+	items := []*fd.Item{}
+	if m.Index == nil {
+		return items
+	}
+
+	for _, result := range m.Index.Search(text, time.Now()) {
+		doc := result.Doc
+		if item := m.findItem(doc.Belong, doc.ItemID); item != nil {
+			items = append(items, item)
+			continue
+		}
+		items = append(items, &fd.Item{
+			ID:      doc.ItemID,
+			Belong:  doc.Belong,
+			Color:   -1,
+			Title:   doc.Title,
+			PubDate: doc.PubDate,
+			Link:    doc.Link,
+			Read:    true,
+		})
+	}
+	return items
+}
+
+func (m *Manager) findItem(belong, id string) *fd.Item {
+	f := m.FindFeed(belong)
+	if f == nil {
+		return nil
+	}
+	for _, item := range f.Items {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apxxxxxxe/rfcui/cache"
+)
+
+func TestIndexIsStoredWithTheFeeds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, testRSS, "blog")
+	}))
+	defer server.Close()
+	cache.DataPath = t.TempDir()
+	cache.CachePath = t.TempDir()
+
+	load := func() *Manager {
+		m := NewManager()
+		if err := m.Load(); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	storedDocs := func(m *Manager) int {
+		docs, err := m.Store.LoadIndex()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(docs)
+	}
+
+	m := load()
+	if _, err := m.AddFeed(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedDocs(m); got != 2 {
+		t.Errorf("got %d stored docs after adding the feed, want 2", got)
+	}
+	m.Close()
+
+	m = load()
+	defer m.Close()
+	if got := len(m.Search("two")); got != 1 {
+		t.Errorf("got %d results after loading, want 1", got)
+	}
+	if err := m.DeleteFeed(m.FindFeed(server.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedDocs(m); got != 0 {
+		t.Errorf("got %d stored docs after deleting the feed, want none", got)
+	}
+}
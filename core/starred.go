@@ -0,0 +1,207 @@
+package core
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/apxxxxxxe/rfcui/cache"
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/store"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	StarredTitle       = "Starred"
+	starredFileName    = "starred.gob"
+	starredGroupColor  = 15
+	starredDescription = "Starred items, kept after they leave their feeds"
+)
+
+var ErrPermanentGroup = errors.New("the group cannot be deleted or renamed")
+
+// starredFile is what the starred items were saved as before they were kept in the store.
+type starredFile struct {
+	Items      []*fd.Item
+	FeedTitles map[string]string
+}
+
+func starredPath() string {
+	return filepath.Join(cache.DataPath, starredFileName)
+}
+
+func newStarredGroup() *fd.Feed {
+	return &fd.Feed{
+		Title:       StarredTitle,
+		Color:       starredGroupColor,
+		Description: starredDescription,
+		FeedLinks:   []string{},
+		Items:       []*fd.Item{},
+	}
+}
+
+// loadStarred reads the starred items into their group.
+func (m *Manager) loadStarred(starred []*store.StarredItem) {
+	m.Starred = newStarredGroup()
+	m.starredFeedTitles = map[string]string{}
+
+	for _, s := range starred {
+		m.Starred.Items = append(m.Starred.Items, s.Item)
+		if s.FeedTitle != "" {
+			m.starredFeedTitles[s.Item.Belong] = s.FeedTitle
+		}
+	}
+	m.Starred.SortItems()
+}
+
+// importStarred copies the starred items from the file they were saved in into the store.
+// A file which cannot be read is put into quarantine.
+func importStarred(tx store.Tx) (*store.Corruption, error) {
+	b, err := ioutil.ReadFile(starredPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file starredFile
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&file); err != nil {
+		return &store.Corruption{Kind: store.KindStarredFile, Name: starredPath(), Err: err},
+			tx.Quarantine(store.KindStarredFile, starredPath(), b)
+	}
+	for _, item := range file.Items {
+		if err := tx.PutStarred(&store.StarredItem{Item: item, FeedTitle: file.FeedTitles[item.Belong]}); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// putStarred stores the copy of item, which is in the starred group.
+func (m *Manager) putStarred(tx store.Tx, item *fd.Item) error {
+	return tx.PutStarred(&store.StarredItem{Item: item, FeedTitle: m.starredFeedTitles[item.Belong]})
+}
+
+// ToggleStar stars the item or unstars it, keeping a copy of the starred item.
+// Nothing is changed if the store cannot be written.
+func (m *Manager) ToggleStar(item *fd.Item) error {
+	starred := !item.Starred
+	f := m.FindFeed(item.Belong)
+	live := m.findItem(item.Belong, item.ID)
+	items := append([]*fd.Item{}, m.Starred.Items...)
+	feedTitle, hasFeedTitle := m.starredFeedTitles[item.Belong]
+
+	item.Starred = starred
+	target := item
+	if live != nil {
+		live.Starred = starred
+		target = live
+	}
+	if starred {
+		m.addStarred(target)
+	} else {
+		m.removeStarred(target)
+	}
+
+	err := m.Store.Update(func(tx store.Tx) error {
+		if f != nil {
+			if err := tx.PutItemState(target); err != nil {
+				return err
+			}
+		}
+		if starred {
+			return m.putStarred(tx, m.Starred.Items[m.findStarred(target.Belong, target.ID)])
+		}
+		return tx.DeleteStarred(target.Belong, target.ID)
+	})
+	if err != nil {
+		item.Starred = !starred
+		if live != nil {
+			live.Starred = !starred
+		}
+		m.Starred.Items = items
+		if hasFeedTitle {
+			m.starredFeedTitles[item.Belong] = feedTitle
+		} else {
+			delete(m.starredFeedTitles, item.Belong)
+		}
+		return err
+	}
+	m.emit(Event{Type: FeedsChanged, Feed: f})
+	m.emit(Event{Type: GroupsChanged})
+	return nil
+}
+
+func (m *Manager) findStarred(belong, id string) int {
+	for i, item := range m.Starred.Items {
+		if item.Belong == belong && item.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// addStarred keeps a copy of item unless it has one.
+func (m *Manager) addStarred(item *fd.Item) bool {
+	if m.findStarred(item.Belong, item.ID) >= 0 {
+		return false
+	}
+	copied := *item
+	copied.Starred = true
+	copied.Categories = append([]string{}, item.Categories...)
+	copied.Filters = append([]string{}, item.Filters...)
+	copied.Enclosures = []*fd.Enclosure{}
+	for _, e := range item.Enclosures {
+		enclosure := *e
+		copied.Enclosures = append(copied.Enclosures, &enclosure)
+	}
+	m.Starred.Items = append(m.Starred.Items, &copied)
+	m.Starred.SortItems()
+
+	if f := m.FindFeed(item.Belong); f != nil {
+		m.starredFeedTitles[item.Belong] = f.Title
+	}
+	return true
+}
+
+func (m *Manager) removeStarred(item *fd.Item) {
+	if i := m.findStarred(item.Belong, item.ID); i >= 0 {
+		m.Starred.Items = append(m.Starred.Items[:i], m.Starred.Items[i+1:]...)
+	}
+}
+
+// collectStarred keeps copies of the items starred by the filters.
+func (m *Manager) collectStarred() error {
+	added := []*fd.Item{}
+	for _, f := range m.Feeds {
+		for _, item := range f.Items {
+			if item.Starred && m.addStarred(item) {
+				added = append(added, m.Starred.Items[m.findStarred(item.Belong, item.ID)])
+			}
+		}
+	}
+	if len(added) == 0 || m.readOnly {
+		return nil
+	}
+	return m.Store.Update(func(tx store.Tx) error {
+		for _, item := range added {
+			if err := m.putStarred(tx, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FeedTitle returns the title of the feed of belong, even if it is no longer subscribed
+// as far as the starred items know it.
+func (m *Manager) FeedTitle(belong string) string {
+	if f := m.FindFeed(belong); f != nil {
+		return f.Title
+	}
+	return m.starredFeedTitles[belong]
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apxxxxxxe/rfcui/cache"
+	fd "github.com/apxxxxxxe/rfcui/feed"
+)
+
+func TestStarredItemsOutliveTheirFeed(t *testing.T) {
+	cache.DataPath = t.TempDir()
+	cache.CachePath = t.TempDir()
+
+	m := NewManager()
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	const feedLink = "https://example.com/feed"
+	item := &fd.Item{ID: "1", Belong: feedLink, Title: "Reference", Link: "https://example.com/1", PubDate: time.Now()}
+	f := &fd.Feed{Title: "Blog", FeedLinks: []string{feedLink}, Items: []*fd.Item{item}}
+	m.Feeds = append(m.Feeds, f)
+
+	if err := m.ToggleStar(item); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DeleteFeed(f); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Close()
+	m = NewManager()
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Starred.Items) != 1 || m.Starred.Items[0].Title != "Reference" || !m.Starred.Items[0].Starred {
+		t.Fatalf("got starred items %v, want the copy of the item", m.Starred.Items)
+	}
+	if got := m.FeedTitle(feedLink); got != "Blog" {
+		t.Errorf("FeedTitle() = %q, want the title of the deleted feed", got)
+	}
+
+	if err := m.ToggleStar(m.Starred.Items[0]); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+	m = NewManager()
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if len(m.Starred.Items) != 0 {
+		t.Errorf("got %d starred items after unstarring, want none", len(m.Starred.Items))
+	}
+}
+
+func TestToggleStarChangesNothingOnFailure(t *testing.T) {
+	cache.DataPath = t.TempDir()
+	cache.CachePath = t.TempDir()
+
+	m := NewManager()
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	const feedLink = "https://example.com/feed"
+	item := &fd.Item{ID: "1", Belong: feedLink, Title: "Reference"}
+	m.Feeds = append(m.Feeds, &fd.Feed{Title: "Blog", FeedLinks: []string{feedLink}, Items: []*fd.Item{item}})
+
+	// the store can no longer be written
+	m.Close()
+	if err := m.ToggleStar(item); err == nil {
+		t.Fatal("ToggleStar() succeeded with the store closed")
+	}
+	if item.Starred || len(m.Starred.Items) != 0 || len(m.starredFeedTitles) != 0 {
+		t.Errorf("the item was starred in memory although it was not stored")
+	}
+}
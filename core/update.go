@@ -0,0 +1,198 @@
+package core
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/filter"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultConcurrency  = 8
+	defaultPerHostLimit = 2
+)
+
+var ErrUpdateInProgress = errors.New("an update is already in progress")
+
+// UpdateFeed refreshes the feed, applies the filters and the retention of the archive to its items and saves it.
+// The result is recorded in the status of the feed; a failure keeps the cached items
+// and puts off the next background retry.
+// The feed is fetched on the calling goroutine and changed through Sync.
+func (m *Manager) UpdateFeed(f *fd.Feed) error {
+	fetched, fetchErr := f.Fetch()
+	var err error
+	m.sync(func() {
+		err = m.applyFetched(f, fetched, fetchErr)
+	})
+	return err
+}
+
+func (m *Manager) applyFetched(f *fd.Feed, fetched *fd.Feed, refreshErr error) error {
+	now := time.Now()
+	f.Status.LastChecked = now
+	seen := filter.Seen(f)
+	if refreshErr != nil {
+		m.recordFailure(f, now, refreshErr)
+	} else {
+		f.Apply(fetched)
+		m.recordSuccess(f, now)
+		filter.Apply(m.Filters, f, seen)
+		m.prune(f, now)
+		if m.Index != nil {
+			m.Index.Add(f)
+		}
+	}
+
+	if err := m.SaveFeed(f); err != nil {
+		f.Status.LastError = err.Error()
+		return err
+	}
+	return refreshErr
+}
+
+// UpdateAll refreshes all feeds, then the groups.
+// At most Concurrency feeds are fetched at once, and at most PerHostLimit from the same host.
+// A FeedUpdated event is emitted as each feed is done; failures do not stop the update.
+func (m *Manager) UpdateAll() error {
+	if !m.beginUpdate() {
+		return ErrUpdateInProgress
+	}
+	defer m.endUpdate()
+
+	var feeds []*fd.Feed
+	m.sync(func() {
+		feeds = make([]*fd.Feed, len(m.Feeds))
+		copy(feeds, m.Feeds)
+	})
+
+	failed := m.refreshFeeds(feeds)
+
+	var err error
+	m.sync(func() {
+		err = m.UpdateGroups()
+	})
+	if err != nil {
+		return err
+	}
+
+	m.emit(Event{Type: UpdateFinished, Done: len(feeds), Total: len(feeds), Failed: failed})
+	return nil
+}
+
+// sync runs fn through Sync, or under the lock of the manager if Sync is not set.
+// It is called from the goroutines of the updates and downloads, never from the one Sync runs fn on.
+func (m *Manager) sync(fn func()) {
+	if m.Sync != nil {
+		m.Sync(fn)
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn()
+}
+
+// prune removes the archived items of f beyond the retention of the feed.
+func (m *Manager) prune(f *fd.Feed, now time.Time) {
+	feedLink, err := f.GetFeedLink()
+	if err != nil {
+		return
+	}
+	f.Prune(m.Archive.RetentionOf(feedLink, f.Title), now)
+}
+
+// beginUpdate reports whether no other update is running, and if so, marks one as running.
+func (m *Manager) beginUpdate() bool {
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+	if m.updating {
+		return false
+	}
+	m.updating = true
+	return true
+}
+
+func (m *Manager) endUpdate() {
+	m.updateMu.Lock()
+	m.updating = false
+	m.updateMu.Unlock()
+}
+
+// refreshFeeds updates the feeds in a bounded pool of workers and returns the number of failures.
+func (m *Manager) refreshFeeds(feeds []*fd.Feed) int {
+	concurrency := m.Concurrency
+	if concurrency < 1 {
+		concurrency = defaultConcurrency
+	}
+	perHostLimit := m.PerHostLimit
+	if perHostLimit < 1 {
+		perHostLimit = defaultPerHostLimit
+	}
+
+	queues := map[string]chan *fd.Feed{}
+	for _, f := range feeds {
+		feedLink, _ := f.GetFeedLink()
+		host := hostOf(feedLink)
+		if _, ok := queues[host]; !ok {
+			queues[host] = make(chan *fd.Feed, len(feeds))
+		}
+		queues[host] <- f
+	}
+
+	var (
+		mu        sync.Mutex
+		doneCount int
+		failed    int
+	)
+	slots := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+
+	// each host gets its own workers so that a busy host does not hold up the others
+	for _, queue := range queues {
+		close(queue)
+		for i := 0; i < perHostLimit; i++ {
+			wg.Add(1)
+			go func(queue chan *fd.Feed) {
+				defer wg.Done()
+				for f := range queue {
+					slots <- struct{}{}
+					err := m.UpdateFeed(f)
+					<-slots
+
+					mu.Lock()
+					doneCount++
+					if err != nil {
+						failed++
+					}
+					m.emit(Event{Type: FeedUpdated, Feed: f, Err: err, Done: doneCount, Total: len(feeds)})
+					mu.Unlock()
+				}
+			}(queue)
+		}
+	}
+
+	wg.Wait()
+	return failed
+}
+
+// hostOf returns the host of feedLink, or feedLink itself if it is a command.
+func hostOf(feedLink string) string {
+	u, err := url.Parse(feedLink)
+	if err != nil || u.Host == "" {
+		return feedLink
+	}
+	return u.Host
+}
+
+// UpdateGroups collects the items of every group from its member feeds.
+func (m *Manager) UpdateGroups() error {
+	m.ResolveGroups()
+	if err := m.collectStarred(); err != nil {
+		return err
+	}
+	m.emit(Event{Type: GroupsChanged})
+	return nil
+}
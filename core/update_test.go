@@ -0,0 +1,83 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/apxxxxxxe/rfcui/cache"
+	"github.com/apxxxxxxe/rfcui/store"
+)
+
+const testRSS = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>%s</title><link>https://example.com/</link>
+<item><title>One</title><link>https://example.com/%[1]s/1</link><guid>%[1]s-1</guid><pubDate>Mon, 04 Apr 2022 12:00:00 +0000</pubDate></item>
+<item><title>Two</title><link>https://example.com/%[1]s/2</link><guid>%[1]s-2</guid><pubDate>Tue, 05 Apr 2022 12:00:00 +0000</pubDate></item>
+</channel></rss>`
+
+// TestUpdateAllChangesFeedsThroughSync reads the feeds on a goroutine of its own, like the TUI does,
+// while they are updated. Run it with -race.
+func TestUpdateAllChangesFeedsThroughSync(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, testRSS, r.URL.Path[1:])
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cache.DataPath = dir
+	s, err := store.Open(filepath.Join(dir, store.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	m := NewManager()
+	m.Store = s
+	for i := 0; i < 8; i++ {
+		m.AddPlaceholderFeed(fmt.Sprintf("%s/feed%d", server.URL, i), "", -1)
+	}
+	m.ResolveGroups()
+
+	updates := make(chan func())
+	m.Sync = func(fn func()) {
+		done := make(chan struct{})
+		updates <- func() {
+			fn()
+			close(done)
+		}
+		<-done
+	}
+	finished := make(chan error)
+	go func() {
+		finished <- m.UpdateAll()
+	}()
+
+	for {
+		select {
+		case fn := <-updates:
+			fn()
+			continue
+		case err := <-finished:
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range m.Feeds {
+				if f.UnreadCount() != 2 {
+					t.Errorf("%s has %d unread items, want 2", f.Title, f.UnreadCount())
+				}
+			}
+			return
+		default:
+		}
+		// what drawing the panes reads
+		for _, f := range m.Feeds {
+			_ = f.Title
+			f.UnreadCount()
+		}
+		for _, g := range m.Groups {
+			m.GroupItems(g)
+		}
+	}
+}
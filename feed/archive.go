@@ -0,0 +1,37 @@
+package feed
+
+import "time"
+
+// Retention limits the archive of a feed, that is, the items which have left it.
+// The items the feed still publishes are always kept.
+type Retention struct {
+	// the most items kept, counting those still published; 0 for no limit
+	MaxItems int `toml:"max_items"`
+	// 0 for no limit
+	MaxAge      time.Duration `toml:"max_age"`
+	KeepStarred bool          `toml:"keep_starred"`
+}
+
+// Prune removes the archived items beyond the retention, newest first, and returns the number of them.
+func (feed *Feed) Prune(r Retention, now time.Time) int {
+	feed.SortItems()
+
+	kept := make([]*Item, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		if !item.Archived || (r.KeepStarred && item.Starred) {
+			kept = append(kept, item)
+			continue
+		}
+		if r.MaxAge > 0 && now.Sub(item.PubDate) > r.MaxAge {
+			continue
+		}
+		if r.MaxItems > 0 && len(kept) >= r.MaxItems {
+			continue
+		}
+		kept = append(kept, item)
+	}
+
+	pruned := len(feed.Items) - len(kept)
+	feed.Items = kept
+	return pruned
+}
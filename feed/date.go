@@ -0,0 +1,119 @@
+package feed
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// layouts of dates seen in feeds, tried in order
+var dateLayouts = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05-07",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 -07:00",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"Mon, 2 Jan 06 15:04:05 -0700",
+	"Mon, 2 Jan 06 15:04:05 MST",
+	"Mon, 2 January 2006 15:04:05 -0700",
+	"Mon, 2 January 2006 15:04:05 MST",
+	"Monday, 2 Jan 2006 15:04:05 -0700",
+	"Monday, 2 Jan 2006 15:04:05 MST",
+	"Monday, 02-Jan-06 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04 MST",
+	"Mon Jan 2 15:04:05 2006",
+	"Mon Jan 2 15:04:05 MST 2006",
+	"Mon Jan 2 15:04:05 -0700 2006",
+	"January 2, 2006 15:04:05 MST",
+	"January 2, 2006",
+}
+
+// offsets of the zone names that time.Parse does not know unless they are local
+var zoneOffsets = map[string]int{
+	"UT":   0,
+	"UTC":  0,
+	"GMT":  0,
+	"Z":    0,
+	"EST":  -5 * 3600,
+	"EDT":  -4 * 3600,
+	"CST":  -6 * 3600,
+	"CDT":  -5 * 3600,
+	"MST":  -7 * 3600,
+	"MDT":  -6 * 3600,
+	"PST":  -8 * 3600,
+	"PDT":  -7 * 3600,
+	"BST":  1 * 3600,
+	"CET":  1 * 3600,
+	"CEST": 2 * 3600,
+	"EET":  2 * 3600,
+	"EEST": 3 * 3600,
+	"IST":  5*3600 + 1800,
+	"JST":  9 * 3600,
+	"KST":  9 * 3600,
+	"AEST": 10 * 3600,
+	"AEDT": 11 * 3600,
+}
+
+// itemDate returns the date of item in UTC: when it was published, or else updated.
+// It reports false if the item has no date that can be parsed.
+func itemDate(item *gofeed.Item) (time.Time, bool) {
+	if item.PublishedParsed != nil {
+		return item.PublishedParsed.UTC(), true
+	}
+	if t, ok := parseTime(item.Published); ok {
+		return t, true
+	}
+	if item.UpdatedParsed != nil {
+		return item.UpdatedParsed.UTC(), true
+	}
+	if t, ok := parseTime(item.Updated); ok {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// parseTime parses clock in any of dateLayouts into UTC.
+// A date without an offset is taken as UTC.
+func parseTime(clock string) (time.Time, bool) {
+	clock = strings.Join(strings.Fields(clock), " ")
+	if clock == "" {
+		return time.Time{}, false
+	}
+
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, clock)
+		if err != nil {
+			continue
+		}
+		return fixZone(t).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// fixZone gives t the offset of its zone name if time.Parse could not tell it.
+func fixZone(t time.Time) time.Time {
+	name, offset := t.Zone()
+	if offset != 0 {
+		return t
+	}
+	if known, ok := zoneOffsets[strings.ToUpper(name)]; ok && known != 0 {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.FixedZone(name, known))
+	}
+	return t
+}
@@ -0,0 +1,64 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		clock string
+		want  time.Time
+		ok    bool
+	}{
+		{"2022-04-05T12:30:00+09:00", time.Date(2022, 4, 5, 3, 30, 0, 0, time.UTC), true},
+		{"2022-04-05T12:30+09:00", time.Date(2022, 4, 5, 3, 30, 0, 0, time.UTC), true},
+		{"2022-04-05T12:30:00", time.Date(2022, 4, 5, 12, 30, 0, 0, time.UTC), true},
+		{"2022-04-05 12:30:00 -0700", time.Date(2022, 4, 5, 19, 30, 0, 0, time.UTC), true},
+		{"2022-04-05", time.Date(2022, 4, 5, 0, 0, 0, 0, time.UTC), true},
+		{"Tue, 5 Apr 2022 12:30:00 GMT", time.Date(2022, 4, 5, 12, 30, 0, 0, time.UTC), true},
+		{"Tue, 5 Apr 2022 12:30:00 EST", time.Date(2022, 4, 5, 17, 30, 0, 0, time.UTC), true},
+		{"Tue, 5 Apr 2022 12:30:00 JST", time.Date(2022, 4, 5, 3, 30, 0, 0, time.UTC), true},
+		{"Tue, 5 Apr 22 12:30:00 +0100", time.Date(2022, 4, 5, 11, 30, 0, 0, time.UTC), true},
+		{"Tuesday, 5 Apr 2022 12:30:00 +0000", time.Date(2022, 4, 5, 12, 30, 0, 0, time.UTC), true},
+		{"  Tue,  5 Apr 2022\n12:30:00 +0000 ", time.Date(2022, 4, 5, 12, 30, 0, 0, time.UTC), true},
+		{"5 Apr 2022 12:30 -0700", time.Date(2022, 4, 5, 19, 30, 0, 0, time.UTC), true},
+		{"Tue Apr 5 12:30:00 2022", time.Date(2022, 4, 5, 12, 30, 0, 0, time.UTC), true},
+		{"April 5, 2022", time.Date(2022, 4, 5, 0, 0, 0, 0, time.UTC), true},
+		{"", time.Time{}, false},
+		{"yesterday", time.Time{}, false},
+		{"2022-13-45", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseTime(tt.clock)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseTime(%q) = %v, %v; want %v, %v", tt.clock, got, ok, tt.want, tt.ok)
+		}
+		if ok && got.Location() != time.UTC {
+			t.Errorf("parseTime(%q) is in %v, want UTC", tt.clock, got.Location())
+		}
+	}
+}
+
+func TestItemDate(t *testing.T) {
+	published := time.Date(2022, 4, 5, 12, 0, 0, 0, time.FixedZone("JST", 9*3600))
+	tests := []struct {
+		name string
+		item *gofeed.Item
+		want time.Time
+		ok   bool
+	}{
+		{"parsed by gofeed", &gofeed.Item{PublishedParsed: &published}, published.UTC(), true},
+		{"published left to us", &gofeed.Item{Published: "Tue, 5 Apr 2022 12:30:00 EST"}, time.Date(2022, 4, 5, 17, 30, 0, 0, time.UTC), true},
+		{"updated instead", &gofeed.Item{Published: "soon", Updated: "2022-04-05T01:00:00Z"}, time.Date(2022, 4, 5, 1, 0, 0, 0, time.UTC), true},
+		{"no date", &gofeed.Item{Published: "soon"}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := itemDate(tt.item)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("%s: itemDate = %v, %v; want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
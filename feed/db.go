@@ -15,9 +15,20 @@ func EncodeFeed(feeds *Feed) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func DecodeFeed(data []byte) *Feed {
+func DecodeFeed(data []byte) (*Feed, error) {
 	var feeds Feed
 	buf := bytes.NewBuffer(data)
-	_ = gob.NewDecoder(buf).Decode(&feeds)
-	return &feeds
+	if err := gob.NewDecoder(buf).Decode(&feeds); err != nil {
+		return nil, err
+	}
+
+	for _, item := range feeds.Items {
+		// items cached before IDs were introduced
+		if item.ID == "" {
+			item.ID = newItemID("", item.Link, item.Title, item.Description)
+		}
+		// items cached in JST
+		item.PubDate = item.PubDate.UTC()
+	}
+	return &feeds, nil
 }
@@ -0,0 +1,80 @@
+package feed
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Enclosure is a file attached to an item, like the audio of a podcast episode.
+type Enclosure struct {
+	URL    string
+	Type   string
+	Length int64
+	// where the file was downloaded to, or "" if it is not
+	Path string
+}
+
+func newEnclosures(src []*gofeed.Enclosure) []*Enclosure {
+	enclosures := []*Enclosure{}
+	for _, e := range src {
+		if e == nil || e.URL == "" {
+			continue
+		}
+		length, _ := strconv.ParseInt(e.Length, 10, 64)
+		enclosures = append(enclosures, &Enclosure{URL: e.URL, Type: e.Type, Length: length})
+	}
+	return enclosures
+}
+
+// FormatSize returns the length of the enclosure like "12.3 MB", or "" if unknown.
+func (e *Enclosure) FormatSize() string {
+	return FormatBytes(e.Length)
+}
+
+func FormatBytes(n int64) string {
+	const unit = 1000
+	if n <= 0 {
+		return ""
+	}
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
+
+// IsDownloaded reports whether any enclosure of the item is downloaded.
+func (a *Item) IsDownloaded() bool {
+	for _, e := range a.Enclosures {
+		if e.Path != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// FindEnclosure returns the enclosure of the item whose URL is url, or nil.
+func (a *Item) FindEnclosure(url string) *Enclosure {
+	for _, e := range a.Enclosures {
+		if e.URL == url {
+			return e
+		}
+	}
+	return nil
+}
+
+// mergeEnclosures returns src, keeping where the enclosures already known were downloaded to.
+func (a *Item) mergeEnclosures(src []*Enclosure) []*Enclosure {
+	for _, e := range src {
+		if old := a.FindEnclosure(e.URL); old != nil {
+			e.Path = old.Path
+		}
+	}
+	return src
+}
@@ -1,7 +1,9 @@
 package feed
 
 import (
+	"fmt"
 	"math/rand"
+	"net/http"
 	"net/url"
 	"os/exec"
 	"sort"
@@ -14,7 +16,19 @@ import (
 	"github.com/pkg/errors"
 )
 
-var ErrGetFeedLinkFailed = errors.New("tried to get feed link from a merged feed")
+var (
+	ErrGetFeedLinkFailed = errors.New("tried to get feed link from a merged feed")
+	ErrNotModified       = errors.New("feed is not modified since the last fetch")
+	ErrGettingFeedFailed = errors.New("failed to get feed")
+)
+
+const UserAgent = "rfcui"
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Location is the timezone the dates of items are shown in.
+// They are kept in UTC.
+var Location = time.Local
 
 type Feed struct {
 	Title       string
@@ -23,6 +37,33 @@ type Feed struct {
 	Link        string
 	FeedLinks   []string
 	Items       []*Item
+
+	// validators of the last response, sent back on the next fetch
+	ETag         string
+	LastModified string
+
+	// interval of background refreshes chosen by the user, or 0 for the default
+	RefreshInterval time.Duration
+	// how often the feed says it is updated, or 0 if unknown
+	UpdateHint time.Duration
+
+	Status Status
+}
+
+// Status is the health of a feed as seen by its refreshes.
+type Status struct {
+	LastChecked time.Time
+	LastSuccess time.Time
+	LastError   string
+	// number of refreshes failed in a row
+	Failures int
+	// the feed is not retried before this time while it is failing
+	NextRetry time.Time
+}
+
+// IsBroken reports whether the last refresh of the feed failed.
+func (s Status) IsBroken() bool {
+	return s.Failures > 0
 }
 
 func IsUrl(str string) bool {
@@ -31,15 +72,28 @@ func IsUrl(str string) bool {
 }
 
 func GetFeedFromURL(url string, forcedTitle string) (*Feed, error) {
+	return GetFeedFromURLIfModified(url, forcedTitle, "", "")
+}
+
+// GetFeedFromURLIfModified works like GetFeedFromURL, but makes a conditional request
+// with the given validators and returns ErrNotModified if the server answers 304.
+func GetFeedFromURLIfModified(url, forcedTitle, etag, lastModified string) (*Feed, error) {
 	var (
 		parsedFeed *gofeed.Feed
 		feed       *Feed
+		resp       *http.Response
 		err        error
 	)
-	parser := gofeed.NewParser()
+	parser := newParser()
 
 	if IsUrl(url) {
-		parsedFeed, err = parser.ParseURL(url)
+		resp, err = conditionalGet(url, etag, lastModified)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		parsedFeed, err = parser.Parse(resp.Body)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
@@ -71,28 +125,43 @@ func GetFeedFromURL(url string, forcedTitle string) (*Feed, error) {
 		Link:        parsedFeed.Link,
 		FeedLinks:   []string{url},
 		Items:       []*Item{},
+		UpdateHint:  updateHint(parsedFeed),
 	}
 
-  jst, err := time.LoadLocation("Asia/Tokyo")
-  if err != nil {
-    return nil,err
-  }
+	if resp != nil {
+		feed.ETag = resp.Header.Get("ETag")
+		feed.LastModified = resp.Header.Get("Last-Modified")
+	}
 
+	now := time.Now()
 	for _, item := range parsedFeed.Items {
 		feedLink, err := feed.GetFeedLink()
 		if err != nil {
 			return nil, err
 		}
-		if time.Now().After(parseTime(item.Published)) {
-			feed.Items = append(feed.Items, &Item{
-				Belong:      feedLink,
-				Color:       feed.Color,
-				Title:       item.Title,
-				Description: item.Description,
-				PubDate:     parseTime(item.Published).In(jst),
-				Link:        item.Link,
-			})
+
+		// 現在時刻より未来の記事を除外
+		pubDate, ok := itemDate(item)
+		if ok && pubDate.After(now) {
+			continue
+		}
+		if !ok {
+			pubDate = now.UTC()
 		}
+		feed.Items = append(feed.Items, &Item{
+			ID:          newItemID(item.GUID, item.Link, item.Title, item.Description),
+			Belong:      feedLink,
+			Color:       feed.Color,
+			Title:       item.Title,
+			Description: item.Description,
+			Content:     item.Content,
+			Enclosures:  newEnclosures(item.Enclosures),
+			PubDate:     pubDate,
+			UnknownDate: !ok,
+			Link:        item.Link,
+			Author:      itemAuthor(item),
+			Categories:  item.Categories,
+		})
 	}
 
 	feed.SortItems()
@@ -100,6 +169,106 @@ func GetFeedFromURL(url string, forcedTitle string) (*Feed, error) {
 	return feed, nil
 }
 
+// Refresh fetches the feed again and merges the fetched items into it.
+// If fetching fails, the feed keeps its title and items as they are and ErrGettingFeedFailed is returned.
+func (feed *Feed) Refresh() error {
+	fetched, err := feed.Fetch()
+	if err != nil {
+		return err
+	}
+	feed.Apply(fetched)
+	return nil
+}
+
+// Fetch gets the feed again without changing it, or nil if it has not been modified.
+// It only reads the feed link and the validators of the feed, which only Apply changes,
+// so the feed may be read elsewhere meanwhile.
+func (feed *Feed) Fetch() (*Feed, error) {
+	url, err := feed.GetFeedLink()
+	if err != nil {
+		return nil, errors.Wrap(err, feed.Title)
+	}
+
+	fetched, err := GetFeedFromURLIfModified(url, "", feed.ETag, feed.LastModified)
+	if errors.Is(err, ErrNotModified) {
+		return nil, nil
+	}
+
+	// keep what we have cached so that a flaky server does not wipe the items
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGettingFeedFailed, err)
+	}
+	return fetched, nil
+}
+
+// Apply merges fetched, a copy of the feed got by Fetch, into the feed.
+func (feed *Feed) Apply(fetched *Feed) {
+	if fetched == nil {
+		return
+	}
+	color := feed.Color
+	if color > 0 && color < len(mycolor.TcellColors) {
+		for _, item := range fetched.Items {
+			item.Color = feed.Color
+		}
+	} else {
+		feed.Title = fetched.Title
+		feed.Color = fetched.Color
+	}
+	feed.Link = fetched.Link
+	feed.Description = fetched.Description
+	feed.ETag = fetched.ETag
+	feed.LastModified = fetched.LastModified
+	feed.UpdateHint = fetched.UpdateHint
+	feed.MergeItems(fetched.Items)
+}
+
+// itemAuthor returns the names of the authors of item joined by commas.
+func itemAuthor(item *gofeed.Item) string {
+	names := []string{}
+	for _, author := range item.Authors {
+		if author != nil && author.Name != "" {
+			names = append(names, author.Name)
+		}
+	}
+	if len(names) == 0 && item.Author != nil {
+		return item.Author.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func conditionalGet(url, etag, lastModified string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, ErrNotModified
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, errors.WithStack(gofeed.HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		})
+	}
+	return resp, nil
+}
+
 func (feed *Feed) GetFeedLink() (string, error) {
 	if feed.IsMerged() {
 		return "", ErrGetFeedLinkFailed
@@ -137,55 +306,83 @@ func MergeFeeds(feeds []*Feed, title string) (*Feed, error) {
 	return resultFeed, nil
 }
 
-func parseTime(clock string) time.Time {
-	const (
-		ISO8601  = "2006-01-02T15:04:05+09:00"
-		ISO8601Z = "2006-01-02T15:04:05Z"
-	)
-	var (
-		tm          time.Time
-		finalFormat = ISO8601
-		formats     = []string{
-			ISO8601,
-			ISO8601Z,
-			time.ANSIC,
-			time.UnixDate,
-			time.RubyDate,
-			time.RFC822,
-			time.RFC822Z,
-			time.RFC850,
-			time.RFC1123,
-			time.RFC1123Z,
-			time.RFC3339,
-			time.RFC3339Nano,
+// CollectItems replaces the items of the merged feed with those of its member feeds.
+func (feed *Feed) CollectItems(feeds []*Feed) {
+	feed.Items = []*Item{}
+	for _, url := range feed.FeedLinks {
+		for _, f := range feeds {
+			feedLink, _ := f.GetFeedLink()
+			if url == feedLink {
+				feed.Items = append(feed.Items, f.Items...)
+				break
+			}
 		}
-	)
+	}
+	feed.SortItems()
+}
 
-	for _, format := range formats {
-		if len(clock) == len(format) {
-			switch len(clock) {
-			case len(ISO8601):
-				if clock[19:20] == "Z" {
-					finalFormat = time.RFC3339
-				} else {
-					finalFormat = ISO8601
-				}
-			case len(time.RubyDate):
-				if clock[3:4] == " " {
-					finalFormat = time.RubyDate
-				} else {
-					finalFormat = time.RFC850
-				}
-			default:
-				finalFormat = format
-			}
-			break
+func (feed *Feed) UnreadCount() int {
+	count := 0
+	for _, item := range feed.Items {
+		if !item.Read && !item.Hidden {
+			count++
+		}
+	}
+	return count
+}
+
+// MergeItems adds items to the feed, archiving the items of the feed which are not in them.
+// An existing item with the same ID is updated in place so that its state survives.
+func (feed *Feed) MergeItems(items []*Item) {
+	incoming := map[string]bool{}
+	for _, item := range items {
+		incoming[item.ID] = true
+	}
+	existing := map[string]*Item{}
+	// items cached before IDs were introduced have their links as IDs,
+	// so they are found by their links and take the IDs the feed gives now
+	byLink := map[string]*Item{}
+	for _, item := range feed.Items {
+		existing[item.ID] = item
+		if item.Link != "" && !incoming[item.ID] {
+			byLink[item.Link] = item
 		}
 	}
 
-	tm, _ = time.Parse(finalFormat, clock)
+	merged := make([]*Item, 0, len(items))
+	seen := map[string]bool{}
+	kept := map[*Item]bool{}
+	for _, item := range items {
+		if seen[item.ID] {
+			continue
+		}
+		seen[item.ID] = true
+		old, ok := existing[item.ID]
+		if !ok {
+			old, ok = byLink[item.Link]
+			ok = ok && !kept[old]
+		}
+		if ok {
+			old.ID = item.ID
+			old.update(item)
+			old.Archived = false
+			kept[old] = true
+			merged = append(merged, old)
+		} else {
+			merged = append(merged, item)
+		}
+	}
 
-	return tm
+	// the items which have left the feed are kept until Prune removes them
+	for _, item := range feed.Items {
+		if !kept[item] {
+			item.Archived = true
+			merged = append(merged, item)
+		}
+	}
+
+	feed.Items = merged
+	feed.SortItems()
 }
 
 func (feed *Feed) SortItems() {
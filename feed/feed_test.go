@@ -0,0 +1,71 @@
+package feed
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeItemsAdoptsIDsOfLegacyItems(t *testing.T) {
+	date := time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC)
+	legacy := &Feed{
+		Title:     "Legacy",
+		FeedLinks: []string{"https://example.com/feed"},
+		Items: []*Item{
+			{Title: "First", Link: "https://example.com/1", PubDate: date, Read: true},
+			{Title: "Second", Link: "https://example.com/2", PubDate: date.Add(time.Hour)},
+		},
+	}
+	b, err := EncodeFeed(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := DecodeFeed(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Items[0].ID != "https://example.com/1" {
+		t.Fatalf("legacy item ID = %q, want its link", f.Items[0].ID)
+	}
+
+	f.MergeItems([]*Item{
+		{ID: "tag:example.com,2022:1", Title: "First", Link: "https://example.com/1", PubDate: date},
+		{ID: "tag:example.com,2022:2", Title: "Second", Link: "https://example.com/2", PubDate: date.Add(time.Hour)},
+	})
+
+	if len(f.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(f.Items))
+	}
+	for _, item := range f.Items {
+		if item.Archived {
+			t.Errorf("%s is archived", item.Title)
+		}
+		if item.ID != "tag:example.com,2022:"+item.Link[len(item.Link)-1:] {
+			t.Errorf("%s has ID %q, want the one fetched", item.Title, item.ID)
+		}
+		if item.Title == "First" && !item.Read {
+			t.Errorf("First lost its read state")
+		}
+	}
+}
+
+func TestMergeItemsKeepsItemsSharingALink(t *testing.T) {
+	f := &Feed{
+		FeedLinks: []string{"https://example.com/feed"},
+		Items: []*Item{
+			{ID: "a", Title: "A", Link: "https://example.com/"},
+		},
+	}
+	f.MergeItems([]*Item{
+		{ID: "a", Title: "A", Link: "https://example.com/"},
+		{ID: "b", Title: "B", Link: "https://example.com/"},
+	})
+
+	if len(f.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(f.Items))
+	}
+	for _, item := range f.Items {
+		if item.Archived {
+			t.Errorf("%s is archived", item.Title)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package feed
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/rss"
+)
+
+// rssTranslator keeps the <ttl> of RSS feeds, which the default translator drops.
+type rssTranslator struct {
+	gofeed.DefaultRSSTranslator
+}
+
+func (t *rssTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	result, err := t.DefaultRSSTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+	if rssFeed, ok := feed.(*rss.Feed); ok && rssFeed.TTL != "" {
+		if result.Custom == nil {
+			result.Custom = map[string]string{}
+		}
+		result.Custom["ttl"] = rssFeed.TTL
+	}
+	return result, nil
+}
+
+func newParser() *gofeed.Parser {
+	parser := gofeed.NewParser()
+	parser.RSSTranslator = &rssTranslator{}
+	return parser
+}
+
+// updateHint returns how often the feed says it is updated,
+// from <ttl> or sy:updatePeriod and sy:updateFrequency. It returns 0 if the feed says nothing.
+func updateHint(feed *gofeed.Feed) time.Duration {
+	if ttl, err := strconv.Atoi(strings.TrimSpace(feed.Custom["ttl"])); err == nil && ttl > 0 {
+		return time.Duration(ttl) * time.Minute
+	}
+
+	sy, ok := feed.Extensions["sy"]
+	if !ok || len(sy["updatePeriod"]) == 0 {
+		return 0
+	}
+
+	var period time.Duration
+	switch strings.TrimSpace(sy["updatePeriod"][0].Value) {
+	case "hourly":
+		period = time.Hour
+	case "daily":
+		period = 24 * time.Hour
+	case "weekly":
+		period = 7 * 24 * time.Hour
+	case "monthly":
+		period = 30 * 24 * time.Hour
+	case "yearly":
+		period = 365 * 24 * time.Hour
+	default:
+		return 0
+	}
+
+	frequency := 1
+	if len(sy["updateFrequency"]) > 0 {
+		if n, err := strconv.Atoi(strings.TrimSpace(sy["updateFrequency"][0].Value)); err == nil && n > 0 {
+			frequency = n
+		}
+	}
+	return period / time.Duration(frequency)
+}
@@ -1,25 +1,119 @@
 package feed
 
 import (
+	"crypto/md5"
+	"fmt"
 	"time"
 )
 
 type Item struct {
+	ID          string
 	Belong      string
 	Color       int
 	Title       string
 	Description string
-	PubDate     time.Time
+	// the full content of the item like content:encoded, if the feed has it
+	Content    string
+	Enclosures []*Enclosure
+	PubDate    time.Time
+	// the feed gives no date of the item; PubDate is when it was first seen
+	UnknownDate bool
 	Link        string
+	Author      string
+	Categories  []string
+	Read        bool
+	// the user marked the item to keep
+	Starred bool
+	// set by the filters named in Filters
+	Hidden      bool
+	Highlighted bool
+	Filters     []string
+	// the item has left the feed and is kept in its archive
+	Archived bool
 }
 
-const timeFormat = "2006/01/02 15:04:05"
+const (
+	timeFormat = "2006/01/02 15:04:05"
+	// RelativeFormat is the layout of dates relative to now
+	RelativeFormat = "relative"
+	unknownDate    = "unknown"
+)
+
+// newItemID returns a value identifying an item across refreshes:
+// its GUID if any, otherwise its link, otherwise a hash of its content.
+func newItemID(guid, link, title, description string) string {
+	if guid != "" {
+		return guid
+	}
+	if link != "" {
+		return link
+	}
+	return fmt.Sprintf("%x", md5.Sum([]byte(title+description)))
+}
+
+// update overwrites the content of the item with that of src, keeping its state.
+func (a *Item) update(src *Item) {
+	a.Belong = src.Belong
+	a.Color = src.Color
+	a.Title = src.Title
+	a.Description = src.Description
+	a.Content = src.Content
+	a.Enclosures = a.mergeEnclosures(src.Enclosures)
+	// an unknown date keeps the one we have
+	if !src.UnknownDate {
+		a.PubDate = src.PubDate
+		a.UnknownDate = false
+	}
+	a.Link = src.Link
+	a.Author = src.Author
+	a.Categories = src.Categories
+}
 
 func (a *Item) FormatDate() string {
-	return a.PubDate.Format(timeFormat)
+	if a.UnknownDate {
+		return unknownDate
+	}
+	return a.PubDate.In(Location).Format(timeFormat)
+}
+
+// FormatDateAs formats the date of the item in Location with layout,
+// or relatively to now like "3h ago" if layout is RelativeFormat.
+func (a *Item) FormatDateAs(layout string) string {
+	if a.UnknownDate {
+		return unknownDate
+	}
+	if layout == RelativeFormat {
+		return formatRelative(a.PubDate, time.Now())
+	}
+	return a.PubDate.In(Location).Format(layout)
+}
+
+func formatRelative(t, now time.Time) string {
+	d := now.Sub(t)
+	suffix := " ago"
+	if d < 0 {
+		d = -d
+		suffix = " later"
+	}
+
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%s", int(d/time.Minute), suffix)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%s", int(d/time.Hour), suffix)
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd%s", int(d/(24*time.Hour)), suffix)
+	default:
+		return t.In(Location).Format("2006/01/02")
+	}
 }
 
-func (a *Item) FormatTime() string {
-	const format = "15:04"
-	return a.PubDate.Format(format)
+// Body returns the content of the item, or its description if it has none.
+func (a *Item) Body() string {
+	if a.Content != "" {
+		return a.Content
+	}
+	return a.Description
 }
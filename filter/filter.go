@@ -0,0 +1,160 @@
+package filter
+
+import (
+	"regexp"
+	"strings"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrUnknownAction = errors.New("unknown action")
+	ErrNoCondition   = errors.New("no condition is given")
+)
+
+// actions of rules
+const (
+	Drop      = "drop"
+	Hide      = "hide"
+	MarkRead  = "read"
+	Highlight = "highlight"
+	Star      = "star"
+)
+
+// Rule applies Action to the items matching all of its patterns.
+// The patterns are regular expressions matched case-insensitively.
+// Feed matches the url or the title of the feed of the item; the rule applies to every feed if it is empty.
+type Rule struct {
+	Name     string `toml:"name"`
+	Feed     string `toml:"feed"`
+	Title    string `toml:"title"`
+	Content  string `toml:"content"`
+	Author   string `toml:"author"`
+	Category string `toml:"category"`
+	Link     string `toml:"link"`
+	Action   string `toml:"action"`
+
+	feed     *regexp.Regexp
+	title    *regexp.Regexp
+	content  *regexp.Regexp
+	author   *regexp.Regexp
+	category *regexp.Regexp
+	link     *regexp.Regexp
+}
+
+// Compile checks the rule and prepares its patterns.
+func (r *Rule) Compile() error {
+	switch r.Action {
+	case Drop, Hide, MarkRead, Highlight, Star:
+	default:
+		return errors.Wrap(ErrUnknownAction, r.Action)
+	}
+
+	patterns := []struct {
+		name   string
+		source string
+		dest   **regexp.Regexp
+	}{
+		{"feed", r.Feed, &r.feed},
+		{"title", r.Title, &r.title},
+		{"content", r.Content, &r.content},
+		{"author", r.Author, &r.author},
+		{"category", r.Category, &r.category},
+		{"link", r.Link, &r.link},
+	}
+	empty := true
+	for _, p := range patterns {
+		if p.source == "" {
+			continue
+		}
+		pattern, err := regexp.Compile("(?i)" + p.source)
+		if err != nil {
+			return errors.Wrap(err, p.name)
+		}
+		*p.dest = pattern
+		empty = false
+	}
+	if empty {
+		return ErrNoCondition
+	}
+	return nil
+}
+
+// Match reports whether the rule applies to item of the feed of feedLink and feedTitle.
+func (r *Rule) Match(item *fd.Item, feedLink, feedTitle string) bool {
+	if r.feed != nil && !r.feed.MatchString(feedLink) && !r.feed.MatchString(feedTitle) {
+		return false
+	}
+	if r.title != nil && !r.title.MatchString(item.Title) {
+		return false
+	}
+	if r.content != nil && !r.content.MatchString(item.Body()) {
+		return false
+	}
+	if r.author != nil && !r.author.MatchString(item.Author) {
+		return false
+	}
+	if r.category != nil && !r.category.MatchString(strings.Join(item.Categories, "\n")) {
+		return false
+	}
+	if r.link != nil && !r.link.MatchString(item.Link) {
+		return false
+	}
+	return true
+}
+
+// Apply applies the rules to the items of f, recording the names of the rules matching each item.
+// Marking as read and starring happen only to the items whose IDs are not in seen,
+// so that the user can undo them; the other actions follow the rules as they are now.
+func Apply(rules []*Rule, f *fd.Feed, seen map[string]bool) {
+	feedLink, err := f.GetFeedLink()
+	if err != nil {
+		return
+	}
+
+	items := []*fd.Item{}
+	for _, item := range f.Items {
+		item.Hidden = false
+		item.Highlighted = false
+		item.Filters = nil
+
+		drop := false
+		for _, r := range rules {
+			if !r.Match(item, feedLink, f.Title) {
+				continue
+			}
+			item.Filters = append(item.Filters, r.Name)
+			switch r.Action {
+			case Drop:
+				drop = true
+			case Hide:
+				item.Hidden = true
+			case Highlight:
+				item.Highlighted = true
+			case MarkRead:
+				if !seen[item.ID] {
+					item.Read = true
+				}
+			case Star:
+				if !seen[item.ID] {
+					item.Starred = true
+				}
+			}
+		}
+		if !drop {
+			items = append(items, item)
+		}
+	}
+	f.Items = items
+}
+
+// Seen returns the IDs of the items of f.
+func Seen(f *fd.Feed) map[string]bool {
+	seen := map[string]bool{}
+	for _, item := range f.Items {
+		seen[item.ID] = true
+	}
+	return seen
+}
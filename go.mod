@@ -3,10 +3,14 @@ module github.com/apxxxxxxe/rfcui
 go 1.17
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/gdamore/tcell/v2 v2.4.1-0.20210905002822-f057f0a857a1
+	github.com/mattn/go-runewidth v0.0.13
 	github.com/mmcdole/gofeed v1.1.3
 	github.com/pkg/errors v0.9.1
 	github.com/rivo/tview v0.0.0-20220307222120-9994674d60a8
+	go.etcd.io/bbolt v1.3.6
+	golang.org/x/net v0.0.0-20200301022130-244492dfa37a
 )
 
 require (
@@ -15,12 +19,10 @@ require (
 	github.com/gdamore/encoding v1.0.0 // indirect
 	github.com/json-iterator/go v1.1.10 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
-	github.com/mattn/go-runewidth v0.0.13 // indirect
 	github.com/mmcdole/goxpp v0.0.0-20181012175147-0068e33feabf // indirect
 	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
 	github.com/modern-go/reflect2 v0.0.0-20180701023420-4b7aa43c6742 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	golang.org/x/net v0.0.0-20200301022130-244492dfa37a // indirect
 	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c // indirect
 	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211 // indirect
 	golang.org/x/text v0.3.6 // indirect
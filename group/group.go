@@ -0,0 +1,106 @@
+package group
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sort"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+)
+
+const defaultColor = 15
+
+// Group is a set of feeds shown together.
+// It keeps no items; they are taken from the member feeds when the group is viewed.
+type Group struct {
+	// stays the same when the group is renamed
+	ID   string
+	Name string
+	// the feed links of the member feeds
+	Members []string
+	// position among the groups, from 0
+	Order   int
+	Options Options
+}
+
+type Options struct {
+	Color int
+}
+
+func New(name string, members []string, order int) *Group {
+	g := &Group{
+		ID:      newID(),
+		Name:    name,
+		Members: []string{},
+		Order:   order,
+		Options: Options{Color: defaultColor},
+	}
+	g.AddMembers(members)
+	return g
+}
+
+// FromFeed makes a group of a merged feed, which groups were before they had their own model.
+func FromFeed(f *fd.Feed, order int) *Group {
+	g := New(f.Title, f.FeedLinks, order)
+	if f.Color > 0 {
+		g.Options.Color = f.Color
+	}
+	return g
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%x", b)
+}
+
+func (g *Group) HasMember(feedLink string) bool {
+	for _, member := range g.Members {
+		if member == feedLink {
+			return true
+		}
+	}
+	return false
+}
+
+// AddMembers adds the feeds of feedLinks which are not members yet.
+func (g *Group) AddMembers(feedLinks []string) {
+	for _, feedLink := range feedLinks {
+		if !g.HasMember(feedLink) {
+			g.Members = append(g.Members, feedLink)
+		}
+	}
+}
+
+func (g *Group) RemoveMember(feedLink string) {
+	for i, member := range g.Members {
+		if member == feedLink {
+			g.Members = append(g.Members[:i], g.Members[i+1:]...)
+			return
+		}
+	}
+}
+
+// Items returns the items of the member feeds among feeds, newest first.
+func (g *Group) Items(feeds []*fd.Feed) []*fd.Item {
+	items := []*fd.Item{}
+	for _, f := range feeds {
+		feedLink, err := f.GetFeedLink()
+		if err == nil && g.HasMember(feedLink) {
+			items = append(items, f.Items...)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].PubDate.After(items[j].PubDate)
+	})
+	return items
+}
+
+// Sort sorts groups by their order.
+func Sort(groups []*Group) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Order < groups[j].Order
+	})
+}
@@ -0,0 +1,259 @@
+package index
+
+import (
+	"crypto/md5"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+)
+
+// Doc is what the index keeps of an item, enough to show it after it has left its feed.
+type Doc struct {
+	Belong  string
+	ItemID  string
+	Title   string
+	Link    string
+	PubDate time.Time
+	// hash of the indexed text to skip unchanged items
+	Hash [md5.Size]byte
+	// term -> number of occurrences
+	Terms map[string]int
+}
+
+type Result struct {
+	Doc   *Doc
+	Score float64
+}
+
+// Index is an inverted index from terms to the items containing them.
+// It records the docs changed since they were last stored, so that only those are written.
+// It is safe for concurrent use.
+type Index struct {
+	// term -> document key -> number of occurrences
+	Postings map[string]map[string]int
+	Docs     map[string]*Doc
+
+	// feed link -> keys of the docs of the feed
+	byFeed map[string]map[string]bool
+	// keys of the docs changed and the docs removed since Stored was called
+	changed map[string]bool
+	removed map[string]*Doc
+
+	mu sync.RWMutex
+}
+
+func New() *Index {
+	return &Index{
+		Postings: map[string]map[string]int{},
+		Docs:     map[string]*Doc{},
+		byFeed:   map[string]map[string]bool{},
+		changed:  map[string]bool{},
+		removed:  map[string]*Doc{},
+	}
+}
+
+// FromDocs makes the index of the stored docs.
+func FromDocs(docs []*Doc) *Index {
+	idx := New()
+	for _, doc := range docs {
+		idx.put(key(doc.Belong, doc.ItemID), doc)
+	}
+	return idx
+}
+
+func key(belong, itemID string) string {
+	return belong + "\x00" + itemID
+}
+
+// Add indexes the items of f, skipping the ones indexed unchanged,
+// and drops the items which are no longer in f, such as the ones pruned from its archive.
+func (idx *Index) Add(f *fd.Feed) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	keys := map[string]bool{}
+	for _, item := range f.Items {
+		k := key(item.Belong, item.ID)
+		keys[k] = true
+		text := item.Title + "\n" + item.Body()
+		hash := md5.Sum([]byte(text))
+		if doc, ok := idx.Docs[k]; ok && doc.Hash == hash {
+			if !doc.PubDate.Equal(item.PubDate) {
+				doc.PubDate = item.PubDate
+				idx.changed[k] = true
+			}
+			continue
+		}
+
+		counts := map[string]int{}
+		for _, term := range Tokenize(text) {
+			counts[term]++
+		}
+		idx.remove(k)
+		idx.put(k, &Doc{
+			Belong:  item.Belong,
+			ItemID:  item.ID,
+			Title:   item.Title,
+			Link:    item.Link,
+			PubDate: item.PubDate,
+			Hash:    hash,
+			Terms:   counts,
+		})
+		delete(idx.removed, k)
+		idx.changed[k] = true
+	}
+
+	// the items which have left the feed for good are dropped, like the ones pruned from its archive
+	// and the ones cached before IDs were introduced, which were indexed by their links
+	feedLink, _ := f.GetFeedLink()
+	for k := range idx.byFeed[feedLink] {
+		if !keys[k] {
+			idx.drop(k)
+		}
+	}
+}
+
+// RemoveFeed drops the items of the feed whose feed link is belong.
+func (idx *Index) RemoveFeed(belong string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for k := range idx.byFeed[belong] {
+		idx.drop(k)
+	}
+}
+
+// Feeds returns the feed links of the items in the index.
+func (idx *Index) Feeds() []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	feedLinks := []string{}
+	for feedLink := range idx.byFeed {
+		feedLinks = append(feedLinks, feedLink)
+	}
+	return feedLinks
+}
+
+// Changes returns the docs changed and the docs removed since Stored was called.
+func (idx *Index) Changes() ([]*Doc, []*Doc) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	changed := make([]*Doc, 0, len(idx.changed))
+	for k := range idx.changed {
+		changed = append(changed, idx.Docs[k])
+	}
+	removed := make([]*Doc, 0, len(idx.removed))
+	for _, doc := range idx.removed {
+		removed = append(removed, doc)
+	}
+	return changed, removed
+}
+
+// Stored forgets the changes, which have been stored.
+func (idx *Index) Stored() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.changed = map[string]bool{}
+	idx.removed = map[string]*Doc{}
+}
+
+func (idx *Index) put(k string, doc *Doc) {
+	for term, n := range doc.Terms {
+		if idx.Postings[term] == nil {
+			idx.Postings[term] = map[string]int{}
+		}
+		idx.Postings[term][k] = n
+	}
+	idx.Docs[k] = doc
+	if idx.byFeed[doc.Belong] == nil {
+		idx.byFeed[doc.Belong] = map[string]bool{}
+	}
+	idx.byFeed[doc.Belong][k] = true
+}
+
+// drop removes the doc of k and records it as removed.
+func (idx *Index) drop(k string) {
+	if doc, ok := idx.Docs[k]; ok {
+		idx.remove(k)
+		delete(idx.changed, k)
+		idx.removed[k] = doc
+	}
+}
+
+func (idx *Index) remove(k string) {
+	doc, ok := idx.Docs[k]
+	if !ok {
+		return
+	}
+	for term := range doc.Terms {
+		delete(idx.Postings[term], k)
+		if len(idx.Postings[term]) == 0 {
+			delete(idx.Postings, term)
+		}
+	}
+	delete(idx.Docs, k)
+	delete(idx.byFeed[doc.Belong], k)
+	if len(idx.byFeed[doc.Belong]) == 0 {
+		delete(idx.byFeed, doc.Belong)
+	}
+}
+
+// Search returns the items containing all the terms of text, most relevant and recent first.
+func (idx *Index) Search(text string, now time.Time) []Result {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	terms := tokenizeQuery(text)
+	if len(terms) == 0 {
+		return []Result{}
+	}
+
+	scores := map[string]float64{}
+	for i, term := range terms {
+		postings := idx.Postings[term]
+		idf := math.Log(float64(len(idx.Docs))/float64(len(postings)+1)) + 1
+		next := map[string]float64{}
+		for k, n := range postings {
+			score, ok := scores[k]
+			if i > 0 && !ok {
+				continue
+			}
+			next[k] = score + (1+math.Log(float64(n)))*idf
+		}
+		scores = next
+	}
+
+	results := make([]Result, 0, len(scores))
+	for k, score := range scores {
+		doc := idx.Docs[k]
+		results = append(results, Result{Doc: doc, Score: score * recency(doc.PubDate, now)})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Doc.PubDate.After(results[j].Doc.PubDate)
+	})
+	return results
+}
+
+// recency weighs down older items, down to half for very old ones.
+func recency(t, now time.Time) float64 {
+	days := now.Sub(t).Hours() / 24
+	if days < 0 {
+		days = 0
+	}
+	return 0.5 + 0.5/(1+days/30)
+}
+
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.Docs)
+}
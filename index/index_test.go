@@ -0,0 +1,108 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+)
+
+const testFeedLink = "https://example.com/feed"
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("<p>Hello, 日本語</p>")
+	want := []string{"hello", "日", "本", "語", "日本", "本語"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() = %q, want %q", got, want)
+	}
+
+	// a query is split into bigrams unless a character stands alone
+	if got, want := tokenizeQuery("日本語 本"), []string{"日本", "本語", "本"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenizeQuery() = %q, want %q", got, want)
+	}
+}
+
+func newTestFeed(now time.Time) *fd.Feed {
+	return &fd.Feed{
+		FeedLinks: []string{testFeedLink},
+		Items: []*fd.Item{
+			{ID: "1", Belong: testFeedLink, Title: "東京の天気", Description: "晴れ", PubDate: now.Add(-24 * time.Hour)},
+			{ID: "2", Belong: testFeedLink, Title: "東京の天気 東京の天気", Description: "雨", PubDate: now.Add(-24 * time.Hour)},
+			{ID: "3", Belong: testFeedLink, Title: "大阪の天気", Description: "曇り", PubDate: now},
+		},
+	}
+}
+
+func TestSearch(t *testing.T) {
+	now := time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)
+	idx := New()
+	idx.Add(newTestFeed(now))
+
+	ids := func(results []Result) []string {
+		got := []string{}
+		for _, r := range results {
+			got = append(got, r.Doc.ItemID)
+		}
+		return got
+	}
+	if got, want := ids(idx.Search("東京", now)), []string{"2", "1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search(東京) = %v, want the item with more matches first: %v", got, want)
+	}
+	if got, want := ids(idx.Search("天気", now)), []string{"2", "3", "1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search(天気) = %v, want %v", got, want)
+	}
+	if got := ids(idx.Search("東京 曇り", now)); len(got) != 0 {
+		t.Errorf("Search(東京 曇り) = %v, want no item with only one of the terms", got)
+	}
+
+	idx.RemoveFeed(testFeedLink)
+	if idx.Len() != 0 || len(idx.Postings) != 0 {
+		t.Errorf("got %d docs and %d terms after removing the feed, want none", idx.Len(), len(idx.Postings))
+	}
+}
+
+func TestChangesAndFromDocs(t *testing.T) {
+	now := time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)
+	idx := New()
+	f := newTestFeed(now)
+	idx.Add(f)
+
+	changed, removed := idx.Changes()
+	if len(changed) != 3 || len(removed) != 0 {
+		t.Fatalf("got %d changed and %d removed docs, want the 3 added", len(changed), len(removed))
+	}
+	idx.Stored()
+
+	// the second item is pruned from the feed
+	f.Items = []*fd.Item{f.Items[0], f.Items[2]}
+	idx.Add(f)
+	changed, removed = idx.Changes()
+	if len(changed) != 0 || len(removed) != 1 || removed[0].ItemID != "2" {
+		t.Errorf("got %d changed and %d removed docs, want only the pruned one removed", len(changed), len(removed))
+	}
+
+	loaded := FromDocs([]*Doc{idx.Docs[key(testFeedLink, "1")], idx.Docs[key(testFeedLink, "3")]})
+	if loaded.Len() != 2 || len(loaded.Search("大阪", now)) != 1 || len(loaded.Search("東京", now)) != 1 {
+		t.Errorf("the index made of the docs has %d docs, want the 2 given", loaded.Len())
+	}
+	if changed, removed := loaded.Changes(); len(changed) != 0 || len(removed) != 0 {
+		t.Errorf("got changes of an index made of stored docs")
+	}
+}
+
+func TestAddDropsDocsOfLegacyItems(t *testing.T) {
+	now := time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)
+	legacy := &fd.Item{ID: "https://example.com/1", Belong: testFeedLink, Title: "東京の天気", Link: "https://example.com/1", PubDate: now}
+	idx := New()
+	idx.Add(&fd.Feed{FeedLinks: []string{testFeedLink}, Items: []*fd.Item{legacy}})
+
+	item := *legacy
+	item.ID = "tag:example.com,2022:1"
+	idx.Add(&fd.Feed{FeedLinks: []string{testFeedLink}, Items: []*fd.Item{&item}})
+
+	results := idx.Search("東京", now)
+	if len(results) != 1 || results[0].Doc.ItemID != item.ID {
+		t.Errorf("got %d results, want only the item with its new ID", len(results))
+	}
+}
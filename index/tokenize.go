@@ -0,0 +1,68 @@
+package index
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// Tokenize splits text into the terms of the index: lowercased words,
+// and for Japanese, Chinese and Korean text, which has no spaces, its characters and bigrams.
+func Tokenize(text string) []string {
+	return tokenize(text, true)
+}
+
+// tokenizeQuery splits a query like Tokenize but only into bigrams where possible,
+// as a character alone matches far more than meant.
+func tokenizeQuery(text string) []string {
+	return tokenize(text, false)
+}
+
+func tokenize(text string, unigrams bool) []string {
+	text = strings.ToLower(html.UnescapeString(tagPattern.ReplaceAllString(text, " ")))
+
+	tokens := []string{}
+	word := []rune{}
+	cjk := []rune{}
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 || (unigrams && len(cjk) > 0) {
+			for _, r := range cjk {
+				tokens = append(tokens, string(r))
+			}
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			tokens = append(tokens, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || r == 'ー'
+}
@@ -1,11 +1,41 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/apxxxxxxe/rfcui/cache"
+	"github.com/apxxxxxxe/rfcui/cli"
+	"github.com/apxxxxxxe/rfcui/config"
+	fd "github.com/apxxxxxxe/rfcui/feed"
 	"github.com/apxxxxxxe/rfcui/tui"
 )
 
 func main() {
-	if err := tui.NewTui().Run(); err != nil {
-		panic(err)
+	flags := flag.NewFlagSet("rfcui", flag.ExitOnError)
+	flags.Usage = func() { cli.PrintUsage(os.Stderr) }
+	configPath := flags.String("config", "", "path of the config file")
+	_ = flags.Parse(os.Args[1:])
+
+	conf, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	cache.Configure(conf)
+	fd.Location = conf.Location()
+
+	if flags.NArg() > 0 {
+		if err := cli.Run(flags.Args(), os.Stdout, conf); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := tui.NewTui(conf).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
 	}
 }
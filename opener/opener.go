@@ -0,0 +1,99 @@
+package opener
+
+import (
+	"mime"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var ErrEmptyCommand = errors.New("command of the opener is empty")
+
+// Rule opens the targets whose URL and MIME type match its patterns with Command.
+// {url}, {title} and {feed} in Command are replaced with those of the target.
+type Rule struct {
+	URL        string `toml:"url"`
+	MIME       string `toml:"mime"`
+	Command    string `toml:"command"`
+	Background bool   `toml:"background"`
+
+	url  *regexp.Regexp
+	mime *regexp.Regexp
+}
+
+// Target is what to open.
+type Target struct {
+	URL   string
+	Title string
+	Feed  string
+	// the type given by the feed, as for enclosures, or else guessed from the URL
+	MIME string
+}
+
+// Compile checks the rule and prepares its patterns.
+func (r *Rule) Compile() error {
+	if strings.TrimSpace(r.Command) == "" {
+		return ErrEmptyCommand
+	}
+
+	var err error
+	if r.URL != "" {
+		if r.url, err = regexp.Compile(r.URL); err != nil {
+			return errors.Wrap(err, "url")
+		}
+	}
+	if r.MIME != "" {
+		if r.mime, err = regexp.Compile(r.MIME); err != nil {
+			return errors.Wrap(err, "mime")
+		}
+	}
+	return nil
+}
+
+func (r *Rule) Match(t Target) bool {
+	if r.url != nil && !r.url.MatchString(t.URL) {
+		return false
+	}
+	if r.mime != nil && !r.mime.MatchString(t.mimeType()) {
+		return false
+	}
+	return true
+}
+
+// Find returns the first rule matching t, or nil.
+func Find(rules []*Rule, t Target) *Rule {
+	for _, r := range rules {
+		if r.Match(t) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Expand returns the command of the rule for t as a shell command line.
+func (r *Rule) Expand(t Target) string {
+	return strings.NewReplacer(
+		"{url}", quote(t.URL),
+		"{title}", quote(t.Title),
+		"{feed}", quote(t.Feed),
+	).Replace(r.Command)
+}
+
+func (t Target) mimeType() string {
+	if t.MIME != "" {
+		return t.MIME
+	}
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return ""
+	}
+	return mime.TypeByExtension(strings.ToLower(path.Ext(u.Path)))
+}
+
+// quote quotes s for sh.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
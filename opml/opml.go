@@ -0,0 +1,162 @@
+package opml
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+
+	"github.com/pkg/errors"
+)
+
+const version = "2.0"
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type Body struct {
+	Outlines []*Outline `xml:"outline"`
+}
+
+// Outline is a feed if XMLURL is set, otherwise a folder of outlines.
+type Outline struct {
+	Text     string     `xml:"text,attr"`
+	Title    string     `xml:"title,attr,omitempty"`
+	Type     string     `xml:"type,attr,omitempty"`
+	XMLURL   string     `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string     `xml:"htmlUrl,attr,omitempty"`
+	Color    int        `xml:"rfcuiColor,attr,omitempty"`
+	Outlines []*Outline `xml:"outline"`
+}
+
+func New(title string) *OPML {
+	return &OPML{
+		Version: version,
+		Head: Head{
+			Title:       title,
+			DateCreated: time.Now().Format(time.RFC1123),
+		},
+	}
+}
+
+func NewFeedOutline(title, xmlURL, htmlURL string, color int) *Outline {
+	return &Outline{
+		Text:    title,
+		Title:   title,
+		Type:    "rss",
+		XMLURL:  xmlURL,
+		HTMLURL: htmlURL,
+		Color:   color,
+	}
+}
+
+func NewFolderOutline(title string) *Outline {
+	return &Outline{
+		Text:     title,
+		Title:    title,
+		Outlines: []*Outline{},
+	}
+}
+
+func (o *Outline) IsFeed() bool {
+	return o.XMLURL != ""
+}
+
+// GetTitle returns the text of the outline, falling back to its title.
+func (o *Outline) GetTitle() string {
+	if o.Text != "" {
+		return o.Text
+	}
+	return o.Title
+}
+
+func Load(path string) (*OPML, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc OPML
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &doc, nil
+}
+
+func (doc *OPML) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return doc.Write(file)
+}
+
+// FromFeeds makes a document of the feeds, with the merged feeds in groups
+// as folders of their member feeds.
+func FromFeeds(feeds, groups []*fd.Feed) *OPML {
+	doc := New("rfcui subscriptions")
+
+	findFeed := func(feedLink string) *fd.Feed {
+		for _, f := range feeds {
+			if link, err := f.GetFeedLink(); err == nil && link == feedLink {
+				return f
+			}
+		}
+		return nil
+	}
+
+	grouped := map[string]bool{}
+	for _, g := range groups {
+		folder := NewFolderOutline(g.Title)
+		for _, feedLink := range g.FeedLinks {
+			grouped[feedLink] = true
+			if f := findFeed(feedLink); f != nil {
+				folder.Outlines = append(folder.Outlines, feedOutline(f))
+			} else {
+				folder.Outlines = append(folder.Outlines, NewFeedOutline(feedLink, feedLink, "", 0))
+			}
+		}
+		doc.Body.Outlines = append(doc.Body.Outlines, folder)
+	}
+
+	for _, f := range feeds {
+		feedLink, err := f.GetFeedLink()
+		if err != nil || grouped[feedLink] {
+			continue
+		}
+		doc.Body.Outlines = append(doc.Body.Outlines, feedOutline(f))
+	}
+
+	return doc
+}
+
+func feedOutline(f *fd.Feed) *Outline {
+	feedLink, _ := f.GetFeedLink()
+	return NewFeedOutline(f.Title, feedLink, f.Link, f.Color)
+}
+
+// Write writes the document to w.
+func (doc *OPML) Write(w io.Writer) error {
+	b, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := w.Write(append([]byte(xml.Header), b...)); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
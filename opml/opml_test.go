@@ -0,0 +1,45 @@
+package opml
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	doc := New("rfcui")
+	folder := NewFolderOutline("News")
+	folder.Outlines = append(folder.Outlines, NewFeedOutline("Go Blog", "https://go.dev/blog/feed.atom", "https://go.dev/blog", 3))
+	doc.Body.Outlines = []*Outline{
+		folder,
+		NewFeedOutline("日本語のフィード", "https://example.jp/rss", "", 0),
+	}
+
+	path := filepath.Join(t.TempDir(), "feeds.opml")
+	if err := doc.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Version != version || loaded.Head != doc.Head {
+		t.Errorf("got version %q and head %+v, want %q and %+v", loaded.Version, loaded.Head, version, doc.Head)
+	}
+	if !reflect.DeepEqual(loaded.Body, doc.Body) {
+		t.Errorf("got outlines %+v, want %+v", loaded.Body.Outlines, doc.Body.Outlines)
+	}
+	if loaded.Body.Outlines[0].IsFeed() || !loaded.Body.Outlines[1].IsFeed() {
+		t.Errorf("the folder and the feed were not told apart")
+	}
+}
+
+func TestGetTitle(t *testing.T) {
+	if got := (&Outline{Title: "Title"}).GetTitle(); got != "Title" {
+		t.Errorf("GetTitle() = %q, want the title when there is no text", got)
+	}
+	if got := (&Outline{Text: "Text", Title: "Title"}).GetTitle(); got != "Text" {
+		t.Errorf("GetTitle() = %q, want the text", got)
+	}
+}
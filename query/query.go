@@ -0,0 +1,248 @@
+package query
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+
+	"github.com/pkg/errors"
+)
+
+const dateLayout = "2006-01-02"
+
+var ErrInvalidValue = errors.New("invalid value")
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+var qualifiers = map[string]bool{
+	"feed":     true,
+	"title":    true,
+	"author":   true,
+	"category": true,
+	"after":    true,
+	"before":   true,
+	"since":    true,
+	"unread":   true,
+	"starred":  true,
+}
+
+// Query selects items by words in their title or content and by qualifiers:
+//
+//	feed:<title or url>  items of the feed
+//	title:<regexp>       items whose title matches the regular expression
+//	author:<name>        items by the author
+//	category:<name>      items in the category
+//	after:<yyyy-mm-dd>   items published on or after the day
+//	before:<yyyy-mm-dd>  items published before the day
+//	since:<period>       items published in the period up to now:
+//	                     today, week, month, <n>d or a duration like 12h
+//	unread:<yes|no>      unread or read items
+//	starred:<yes|no>     starred or unstarred items
+//
+// Words and values can be quoted like "two words". Matching is case-insensitive.
+// Any other word with a colon, like 10:30 or re:, is a word to search for.
+type Query struct {
+	// the text the query was parsed from
+	Source   string
+	Words    []string
+	Feed     string
+	Title    *regexp.Regexp
+	Author   string
+	Category string
+	After    time.Time
+	Before   time.Time
+	Since    string
+	// nil matches both
+	Unread  *bool
+	Starred *bool
+}
+
+func Parse(s string) (*Query, error) {
+	q := &Query{Source: s, Words: []string{}}
+	for _, token := range tokenize(s) {
+		key, value := "", token
+		if i := strings.Index(token, ":"); i > 0 && !strings.HasPrefix(token, `"`) && qualifiers[strings.ToLower(token[:i])] {
+			key, value = strings.ToLower(token[:i]), unquote(token[i+1:])
+		}
+
+		switch key {
+		case "":
+			q.Words = append(q.Words, strings.ToLower(unquote(value)))
+		case "feed":
+			q.Feed = strings.ToLower(value)
+		case "title":
+			pattern, err := regexp.Compile("(?i)" + value)
+			if err != nil {
+				return nil, errors.Wrap(ErrInvalidValue, token)
+			}
+			q.Title = pattern
+		case "author":
+			q.Author = strings.ToLower(value)
+		case "category":
+			q.Category = strings.ToLower(value)
+		case "after", "before":
+			day, err := time.ParseInLocation(dateLayout, value, fd.Location)
+			if err != nil {
+				return nil, errors.Wrap(ErrInvalidValue, token)
+			}
+			if key == "after" {
+				q.After = day
+			} else {
+				q.Before = day
+			}
+		case "since":
+			if _, ok := periodStart(value, time.Now()); !ok {
+				return nil, errors.Wrap(ErrInvalidValue, token)
+			}
+			q.Since = strings.ToLower(value)
+		case "unread", "starred":
+			b, ok := parseBool(value)
+			if !ok {
+				return nil, errors.Wrap(ErrInvalidValue, token)
+			}
+			if key == "unread" {
+				q.Unread = &b
+			} else {
+				q.Starred = &b
+			}
+		}
+	}
+	return q, nil
+}
+
+func (q *Query) IsEmpty() bool {
+	return len(q.Words) == 0 && q.Feed == "" && q.Title == nil && q.Author == "" && q.Category == "" &&
+		q.After.IsZero() && q.Before.IsZero() && q.Since == "" && q.Unread == nil && q.Starred == nil
+}
+
+// Match reports whether item matches the query. feedTitle is the title of the feed of item.
+func (q *Query) Match(item *fd.Item, feedTitle string) bool {
+	if q.Feed != "" &&
+		!strings.Contains(strings.ToLower(feedTitle), q.Feed) &&
+		!strings.Contains(strings.ToLower(item.Belong), q.Feed) {
+		return false
+	}
+	if q.Title != nil && !q.Title.MatchString(item.Title) {
+		return false
+	}
+	if q.Author != "" && !strings.Contains(strings.ToLower(item.Author), q.Author) {
+		return false
+	}
+	if q.Category != "" && !hasCategory(item, q.Category) {
+		return false
+	}
+	if !q.After.IsZero() && item.PubDate.Before(q.After) {
+		return false
+	}
+	if q.Since != "" {
+		if start, _ := periodStart(q.Since, time.Now()); item.PubDate.Before(start) {
+			return false
+		}
+	}
+	if !q.Before.IsZero() && !item.PubDate.Before(q.Before) {
+		return false
+	}
+	if q.Unread != nil && *q.Unread == item.Read {
+		return false
+	}
+	if q.Starred != nil && *q.Starred != item.Starred {
+		return false
+	}
+	if len(q.Words) == 0 {
+		return true
+	}
+
+	title := strings.ToLower(item.Title)
+	body := ""
+	for _, word := range q.Words {
+		if strings.Contains(title, word) {
+			continue
+		}
+		if body == "" {
+			body = strings.ToLower(tagPattern.ReplaceAllString(item.Body(), " "))
+		}
+		if !strings.Contains(body, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasCategory(item *fd.Item, category string) bool {
+	for _, c := range item.Categories {
+		if strings.Contains(strings.ToLower(c), category) {
+			return true
+		}
+	}
+	return false
+}
+
+// periodStart returns when the period named by s began as of now.
+// Days and weeks begin at midnight in fd.Location, and weeks on Monday.
+func periodStart(s string, now time.Time) (time.Time, bool) {
+	now = now.In(fd.Location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch s = strings.ToLower(s); s {
+	case "today":
+		return today, true
+	case "week":
+		return today.AddDate(0, 0, -(int(today.Weekday())+6)%7), true
+	case "month":
+		return today.AddDate(0, 0, 1-today.Day()), true
+	}
+
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days < 0 {
+			return time.Time{}, false
+		}
+		return now.AddDate(0, 0, -days), true
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, false
+	}
+	return now.Add(-d), true
+}
+
+// tokenize splits s by spaces outside double quotes.
+func tokenize(s string) []string {
+	tokens := []string{}
+	token := ""
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			token += string(r)
+		case r == ' ' && !quoted:
+			if token != "" {
+				tokens = append(tokens, token)
+			}
+			token = ""
+		default:
+			token += string(r)
+		}
+	}
+	if token != "" {
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func unquote(s string) string {
+	return strings.Trim(s, `"`)
+}
+
+func parseBool(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "yes", "true", "1":
+		return true, true
+	case "no", "false", "0":
+		return false, true
+	}
+	return false, false
+}
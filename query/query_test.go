@@ -0,0 +1,63 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	yes := true
+	tests := []struct {
+		s        string
+		words    []string
+		feed     string
+		author   string
+		category string
+		since    string
+		unread   *bool
+	}{
+		{s: "Go release", words: []string{"go", "release"}},
+		{s: `"two words" more`, words: []string{"two words", "more"}},
+		{s: "10:30", words: []string{"10:30"}},
+		{s: "re: foo", words: []string{"re:", "foo"}},
+		{s: "meeting at 10:30 RE:plans", words: []string{"meeting", "at", "10:30", "re:plans"}},
+		{s: "https://example.com/post", words: []string{"https://example.com/post"}},
+		{s: `"feed:quoted"`, words: []string{"feed:quoted"}},
+		{s: "feed:https://example.com/rss go", words: []string{"go"}, feed: "https://example.com/rss"},
+		{s: `Feed:"Go Blog" author:Rob category:news`, words: []string{}, feed: "go blog", author: "rob", category: "news"},
+		{s: "since:week unread:yes", words: []string{}, since: "week", unread: &yes},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.s)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.s, err)
+			continue
+		}
+		if !reflect.DeepEqual(q.Words, tt.words) {
+			t.Errorf("Parse(%q).Words = %q, want %q", tt.s, q.Words, tt.words)
+		}
+		if q.Feed != tt.feed || q.Author != tt.author || q.Category != tt.category || q.Since != tt.since {
+			t.Errorf("Parse(%q) = feed %q, author %q, category %q, since %q; want %q, %q, %q, %q",
+				tt.s, q.Feed, q.Author, q.Category, q.Since, tt.feed, tt.author, tt.category, tt.since)
+		}
+		if !reflect.DeepEqual(q.Unread, tt.unread) {
+			t.Errorf("Parse(%q).Unread = %v, want %v", tt.s, q.Unread, tt.unread)
+		}
+	}
+}
+
+func TestParseInvalidValue(t *testing.T) {
+	for _, s := range []string{
+		"title:(",
+		"after:yesterday",
+		"before:2022-13-01",
+		"since:fortnight",
+		"unread:maybe",
+		"starred:",
+	} {
+		if _, err := Parse(s); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("Parse(%q) = %v, want ErrInvalidValue", s, err)
+		}
+	}
+}
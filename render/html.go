@@ -0,0 +1,415 @@
+package render
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/tview"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	headingColor = "yellow"
+	codeColor    = "green"
+	linkColor    = "blue"
+	quotePrefix  = "│ "
+)
+
+type Link struct {
+	URL   string
+	Text  string
+	Image bool
+	// MIME type if known, like that of an enclosure
+	Type string
+}
+
+// Page is an HTML document rendered as text with tview color tags.
+// The links and images are referred to as [1], [2]... in the text, in order.
+type Page struct {
+	Text  string
+	Links []Link
+}
+
+type list struct {
+	ordered bool
+	count   int
+}
+
+type renderer struct {
+	base *url.URL
+	buf  strings.Builder
+	page *Page
+
+	// nesting depths of the styles
+	bold, italic, underline, strike int
+	colors                          []string
+
+	pre    int
+	quote  int
+	lists  []list
+	indent string
+
+	// number of newlines at the end of buf
+	newlines int
+	// whether the text written last ends with a space
+	space bool
+}
+
+// HTML renders src, resolving relative links against base.
+func HTML(src, base string) *Page {
+	r := &renderer{page: &Page{Links: []Link{}}, newlines: 2}
+	if u, err := url.Parse(base); err == nil {
+		r.base = u
+	}
+
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		r.text(src)
+	} else {
+		r.walk(doc)
+	}
+
+	r.page.Text = strings.TrimRight(r.buf.String(), "\n")
+	return r.page
+}
+
+// WithReferences returns the text followed by the list of the links.
+func (p *Page) WithReferences() string {
+	if len(p.Links) == 0 {
+		return p.Text
+	}
+	s := p.Text + "\n\n[" + headingColor + "::b]Links[-::-]\n"
+	for i, link := range p.Links {
+		kind := ""
+		if link.Image {
+			kind = "(image) "
+		} else if link.Type != "" {
+			kind = "(" + tview.Escape(link.Type) + ") "
+		}
+		s += fmt.Sprintf("%s %s%s\n", reference(i+1), kind, tview.Escape(link.URL))
+	}
+	return s
+}
+
+func reference(n int) string {
+	return tview.Escape("[" + strconv.Itoa(n) + "]")
+}
+
+func (r *renderer) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		r.children(n)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Noscript, atom.Template:
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		r.block(2)
+		r.bold++
+		r.colors = append(r.colors, headingColor)
+		r.style()
+		r.children(n)
+		r.colors = r.colors[:len(r.colors)-1]
+		r.bold--
+		r.style()
+		r.block(2)
+	case atom.P, atom.Table, atom.Figure, atom.Dl:
+		r.block(2)
+		r.children(n)
+		r.block(2)
+	case atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Tr, atom.Dt, atom.Dd, atom.Figcaption:
+		r.block(1)
+		r.children(n)
+		r.block(1)
+	case atom.Br:
+		r.newline()
+	case atom.Hr:
+		r.block(1)
+		r.write("────────")
+		r.block(1)
+	case atom.Ul, atom.Ol:
+		if len(r.lists) == 0 {
+			r.block(2)
+		} else {
+			r.block(1)
+		}
+		r.lists = append(r.lists, list{ordered: n.DataAtom == atom.Ol})
+		r.children(n)
+		r.lists = r.lists[:len(r.lists)-1]
+		if len(r.lists) == 0 {
+			r.block(2)
+		} else {
+			r.block(1)
+		}
+	case atom.Li:
+		r.item(n)
+	case atom.Blockquote:
+		r.block(2)
+		r.quote++
+		r.italic++
+		r.style()
+		r.children(n)
+		r.italic--
+		r.style()
+		r.quote--
+		r.block(2)
+	case atom.Pre:
+		r.block(2)
+		r.pre++
+		r.colors = append(r.colors, codeColor)
+		r.style()
+		r.children(n)
+		r.colors = r.colors[:len(r.colors)-1]
+		r.style()
+		r.pre--
+		r.block(2)
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		r.colors = append(r.colors, codeColor)
+		r.style()
+		r.children(n)
+		r.colors = r.colors[:len(r.colors)-1]
+		r.style()
+	case atom.Strong, atom.B:
+		r.styled(n, &r.bold)
+	case atom.Em, atom.I, atom.Cite:
+		r.styled(n, &r.italic)
+	case atom.U, atom.Ins:
+		r.styled(n, &r.underline)
+	case atom.S, atom.Del, atom.Strike:
+		r.styled(n, &r.strike)
+	case atom.A:
+		r.anchor(n)
+	case atom.Img:
+		r.image(n)
+	case atom.Td, atom.Th:
+		r.children(n)
+		r.write(" ")
+	default:
+		r.children(n)
+	}
+}
+
+func (r *renderer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.walk(c)
+	}
+}
+
+func (r *renderer) styled(n *html.Node, depth *int) {
+	*depth++
+	r.style()
+	r.children(n)
+	*depth--
+	r.style()
+}
+
+func (r *renderer) item(n *html.Node) {
+	r.block(1)
+	marker := "• "
+	if len(r.lists) > 0 {
+		l := &r.lists[len(r.lists)-1]
+		l.count++
+		if l.ordered {
+			marker = strconv.Itoa(l.count) + ". "
+		}
+	}
+
+	outer := r.indent
+	r.write(marker)
+	// continuation lines and nested lists line up with the text after the marker
+	r.indent = outer + strings.Repeat(" ", len([]rune(marker)))
+	r.children(n)
+	r.indent = outer
+	r.block(1)
+}
+
+func (r *renderer) anchor(n *html.Node) {
+	href := r.resolve(attr(n, "href"))
+	if href == "" {
+		r.children(n)
+		return
+	}
+
+	r.underline++
+	r.style()
+	r.children(n)
+	r.underline--
+	r.style()
+
+	r.reference(Link{URL: href, Text: strings.TrimSpace(textOf(n))})
+}
+
+func (r *renderer) image(n *html.Node) {
+	alt := strings.TrimSpace(attr(n, "alt"))
+	src := r.resolve(attr(n, "src"))
+	if src == "" {
+		if alt != "" {
+			r.text("(image: " + alt + ")")
+		}
+		return
+	}
+
+	label := "image"
+	if alt != "" {
+		label += ": " + alt
+	}
+	r.text("(" + label + ")")
+	r.reference(Link{URL: src, Text: alt, Image: true})
+}
+
+// reference numbers link and writes its number.
+func (r *renderer) reference(link Link) {
+	// the same target is referred to by the same number
+	n := 0
+	for i, l := range r.page.Links {
+		if l.URL == link.URL && l.Image == link.Image {
+			n = i + 1
+			break
+		}
+	}
+	if n == 0 {
+		r.page.Links = append(r.page.Links, link)
+		n = len(r.page.Links)
+	}
+	r.write("[" + linkColor + "]" + reference(n) + "[" + r.color() + "]")
+}
+
+// resolve returns href as an absolute URL, or "" if it does not lead to another page.
+func (r *renderer) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	lower := strings.ToLower(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(lower, "javascript:") || strings.HasPrefix(lower, "data:") {
+		return ""
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if r.base != nil {
+		u = r.base.ResolveReference(u)
+	}
+	return u.String()
+}
+
+// text writes s, collapsing whitespace unless in a pre block.
+func (r *renderer) text(s string) {
+	if r.pre > 0 {
+		lines := strings.Split(s, "\n")
+		for i, line := range lines {
+			if i > 0 {
+				r.newline()
+			}
+			if line != "" {
+				r.write(tview.Escape(line))
+			}
+		}
+		return
+	}
+
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		if s != "" && r.newlines == 0 && !r.space {
+			r.write(" ")
+		}
+		return
+	}
+
+	text := strings.Join(words, " ")
+	if startsWithSpace(s) && r.newlines == 0 && !r.space {
+		text = " " + text
+	}
+	if endsWithSpace(s) {
+		text += " "
+	}
+	r.write(tview.Escape(text))
+}
+
+// write appends s to the current line, starting it with the prefixes of the blocks if needed.
+func (r *renderer) write(s string) {
+	if s == "" {
+		return
+	}
+	if r.newlines > 0 {
+		r.buf.WriteString(strings.Repeat(quotePrefix, r.quote) + r.indent)
+	}
+	r.buf.WriteString(s)
+	r.newlines = 0
+	r.space = strings.HasSuffix(s, " ")
+}
+
+func (r *renderer) newline() {
+	r.buf.WriteString("\n")
+	r.newlines++
+}
+
+// block ends the current line and leaves n-1 empty lines after it.
+func (r *renderer) block(n int) {
+	for r.newlines < n {
+		r.newline()
+	}
+}
+
+// style writes the tag of the current style.
+func (r *renderer) style() {
+	attrs := ""
+	if r.bold > 0 {
+		attrs += "b"
+	}
+	if r.italic > 0 {
+		attrs += "i"
+	}
+	if r.underline > 0 {
+		attrs += "u"
+	}
+	if r.strike > 0 {
+		attrs += "s"
+	}
+	if attrs == "" {
+		attrs = "-"
+	}
+	// tags do not need the line prefixes
+	r.buf.WriteString("[" + r.color() + "::" + attrs + "]")
+}
+
+func (r *renderer) color() string {
+	if len(r.colors) == 0 {
+		return "-"
+	}
+	return r.colors[len(r.colors)-1]
+}
+
+func startsWithSpace(s string) bool {
+	return s != "" && strings.TrimLeft(s, " \t\r\n") != s
+}
+
+func endsWithSpace(s string) bool {
+	return s != "" && strings.TrimRight(s, " \t\r\n") != s
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textOf(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	s := ""
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		s += textOf(c)
+	}
+	return s
+}
@@ -0,0 +1,296 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"time"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/group"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+const FileName = "rfcui.db"
+
+var (
+	ErrLocked        = errors.New("the database is used by another rfcui; quit it or wait for its update to finish")
+	ErrOldSchema     = errors.New("the database was written by an older rfcui; run rfcui once to upgrade it")
+	ErrNotFeedRecord = errors.New("a merged feed cannot be stored as a feed")
+)
+
+var (
+	feedsBucket  = []byte("feeds")
+	groupsBucket = []byte("groups")
+	itemsBucket  = []byte("items")
+	statesBucket = []byte("states")
+	metaBucket   = []byte("meta")
+)
+
+// boltStore keeps the database open, and with it the lock on the file, until it is closed,
+// so that the items one rfcui has loaded are never rewritten by another under it.
+type boltStore struct {
+	db       *bolt.DB
+	migrated bool
+}
+
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+// Open opens the database at path, making it if there is none.
+// It fails with ErrLocked while another rfcui has it open.
+func Open(path string) (Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, errors.Wrap(ErrLocked, path)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range buckets() {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	migrated := false
+	if err == nil {
+		migrated, err = migrateSchema(db)
+	}
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, path)
+	}
+	return &boltStore{db: db, migrated: migrated}, nil
+}
+
+// OpenReadOnly opens the database at path only to read it; nothing is migrated or written.
+// It fails with ErrOldSchema if the database has to be migrated first,
+// and with an error satisfying os.IsNotExist if there is none.
+func OpenReadOnly(path string) (Store, error) {
+	// bolt would make the file
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second, ReadOnly: true})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, errors.Wrap(ErrLocked, path)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+
+	err = db.View(func(tx *bolt.Tx) error {
+		for _, name := range buckets() {
+			if tx.Bucket(name) == nil {
+				return ErrOldSchema
+			}
+		}
+		var version byte
+		if v := tx.Bucket(metaBucket).Get([]byte(versionKey)); len(v) == 1 {
+			version = v[0]
+		}
+		if version < Version {
+			return ErrOldSchema
+		}
+		if version > Version {
+			return errors.Wrapf(ErrDatabaseTooNew, "schema version %d", version)
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, path)
+	}
+	return &boltStore{db: db}, nil
+}
+
+func buckets() [][]byte {
+	return [][]byte{feedsBucket, groupsBucket, itemsBucket, statesBucket, metaBucket, quarantineBucket, starredBucket, searchesBucket, indexBucket}
+}
+
+func (s *boltStore) Migrated() bool {
+	return s.migrated
+}
+
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *boltStore) Update(fn func(Tx) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return fn(&boltTx{tx: tx})
+	})
+}
+
+func (s *boltStore) Load() ([]*fd.Feed, []*group.Group, error) {
+	feeds := []*fd.Feed{}
+	groups := []*group.Group{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		states := tx.Bucket(statesBucket)
+		items := tx.Bucket(itemsBucket)
+
+		err := tx.Bucket(feedsBucket).ForEach(func(k, v []byte) error {
+			f := &fd.Feed{}
+			if err := decode(v, f); err != nil {
+				return errors.Wrap(err, string(k))
+			}
+			f.Items = []*fd.Item{}
+			if b := items.Bucket(k); b != nil {
+				err := b.ForEach(func(id, v []byte) error {
+					item := &fd.Item{}
+					if err := decode(v, item); err != nil {
+						return errors.Wrap(err, string(k))
+					}
+					var state itemState
+					if v := states.Get(stateKey(string(k), string(id))); v != nil {
+						if err := decode(v, &state); err != nil {
+							return errors.Wrap(err, string(k))
+						}
+					}
+					state.applyTo(item)
+					f.Items = append(f.Items, item)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+			f.SortItems()
+			feeds = append(feeds, f)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(groupsBucket).ForEach(func(k, v []byte) error {
+			g := &group.Group{}
+			if err := decode(v, g); err != nil {
+				return errors.Wrap(err, string(k))
+			}
+			groups = append(groups, g)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	group.Sort(groups)
+	return feeds, groups, nil
+}
+
+func (t *boltTx) PutFeed(f *fd.Feed) error {
+	feedLink, err := f.GetFeedLink()
+	if err != nil {
+		return errors.Wrap(ErrNotFeedRecord, f.Title)
+	}
+	key := []byte(feedLink)
+
+	// the items are kept apart from the feed
+	record := *f
+	record.Items = nil
+	if err := put(t.tx.Bucket(feedsBucket), key, &record); err != nil {
+		return err
+	}
+
+	items := t.tx.Bucket(itemsBucket)
+	if items.Bucket(key) != nil {
+		if err := items.DeleteBucket(key); err != nil {
+			return err
+		}
+	}
+	b, err := items.CreateBucket(key)
+	if err != nil {
+		return err
+	}
+	ids := map[string]bool{}
+	for _, item := range f.Items {
+		ids[item.ID] = true
+		// the state is kept apart from the item
+		record := *item
+		itemState{}.applyTo(&record)
+		if err := put(b, []byte(item.ID), &record); err != nil {
+			return err
+		}
+		if err := put(t.tx.Bucket(statesBucket), stateKey(feedLink, item.ID), stateOf(item)); err != nil {
+			return err
+		}
+	}
+	return t.deleteStates(feedLink, ids)
+}
+
+func (t *boltTx) DeleteFeed(feedLink string) error {
+	key := []byte(feedLink)
+	if err := t.tx.Bucket(feedsBucket).Delete(key); err != nil {
+		return err
+	}
+	items := t.tx.Bucket(itemsBucket)
+	if items.Bucket(key) != nil {
+		if err := items.DeleteBucket(key); err != nil {
+			return err
+		}
+	}
+	return t.deleteStates(feedLink, map[string]bool{})
+}
+
+// deleteStates deletes the states of the items of the feed whose IDs are not in keep.
+func (t *boltTx) deleteStates(feedLink string, keep map[string]bool) error {
+	prefix := stateKey(feedLink, "")
+	states := t.tx.Bucket(statesBucket)
+	stale := [][]byte{}
+	c := states.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		if !keep[string(k[len(prefix):])] {
+			stale = append(stale, append([]byte{}, k...))
+		}
+	}
+	for _, k := range stale {
+		if err := states.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *boltTx) PutGroup(g *group.Group) error {
+	return put(t.tx.Bucket(groupsBucket), []byte(g.ID), g)
+}
+
+func (t *boltTx) DeleteGroup(id string) error {
+	return t.tx.Bucket(groupsBucket).Delete([]byte(id))
+}
+
+func (t *boltTx) PutItemState(item *fd.Item) error {
+	return put(t.tx.Bucket(statesBucket), stateKey(item.Belong, item.ID), stateOf(item))
+}
+
+func (t *boltTx) Meta(key string) []byte {
+	return t.tx.Bucket(metaBucket).Get([]byte(key))
+}
+
+func (t *boltTx) PutMeta(key string, value []byte) error {
+	return t.tx.Bucket(metaBucket).Put([]byte(key), value)
+}
+
+// stateKey joins the feed link and the ID of an item with a byte neither of them has.
+func stateKey(feedLink, id string) []byte {
+	return []byte(feedLink + "\x00" + id)
+}
+
+func put(b *bolt.Bucket, key []byte, v interface{}) error {
+	data, err := encode(v)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/group"
+
+	"github.com/pkg/errors"
+)
+
+const testFeedLink = "https://example.com/feed"
+
+func newTestFeed(ids ...string) *fd.Feed {
+	f := &fd.Feed{Title: "Blog", FeedLinks: []string{testFeedLink}, Items: []*fd.Item{}}
+	for i, id := range ids {
+		f.Items = append(f.Items, &fd.Item{ID: id, Belong: testFeedLink, Title: id, PubDate: time.Date(2022, 4, i+1, 0, 0, 0, 0, time.UTC)})
+	}
+	return f
+}
+
+func openTestStore(t *testing.T) Store {
+	s, err := Open(filepath.Join(t.TempDir(), FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestPutFeedKeepsItemStates(t *testing.T) {
+	s := openTestStore(t)
+
+	f := newTestFeed("1", "2")
+	f.Items[0].Read = true
+	if err := s.Update(func(tx Tx) error { return tx.PutFeed(f) }); err != nil {
+		t.Fatal(err)
+	}
+	feeds, _, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 1 || len(feeds[0].Items) != 2 {
+		t.Fatalf("got %v, want the feed with its 2 items", feeds)
+	}
+	for _, item := range feeds[0].Items {
+		if item.Read != (item.ID == "1") {
+			t.Errorf("item %s: Read = %v", item.ID, item.Read)
+		}
+	}
+
+	// the state of an item that left the feed goes with it
+	err = s.Update(func(tx Tx) error {
+		if err := tx.PutFeed(newTestFeed("2")); err != nil {
+			return err
+		}
+		return tx.PutFeed(newTestFeed("1", "2"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feeds, _, err = s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range feeds[0].Items {
+		if item.Read {
+			t.Errorf("item %s is still read after it left the feed", item.ID)
+		}
+	}
+
+	if err := s.Update(func(tx Tx) error { return tx.DeleteFeed(testFeedLink) }); err != nil {
+		t.Fatal(err)
+	}
+	feeds, _, err = s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 0 {
+		t.Errorf("got %d feeds after deleting it, want none", len(feeds))
+	}
+}
+
+func TestMigrateCache(t *testing.T) {
+	s := openTestStore(t)
+
+	cacheDir := t.TempDir()
+	b, err := fd.EncodeFeed(newTestFeed("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(cacheDir, "feed"), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []int{1, 0} {
+		count, _, err := MigrateCache(s, cacheDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != want {
+			t.Errorf("MigrateCache() = %d, want %d", count, want)
+		}
+	}
+	feeds, _, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 1 || len(feeds[0].Items) != 1 {
+		t.Errorf("got %v, want the cached feed", feeds)
+	}
+}
+
+// TestOpenLocked opens the database as an update run by cron would while the TUI has it open.
+func TestOpenLocked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	tui, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tui.Update(func(tx Tx) error {
+		return tx.PutGroup(group.New("News", []string{testFeedLink}, 0))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cron, err := Open(path); !errors.Is(err, ErrLocked) {
+		if err == nil {
+			cron.Close()
+		}
+		t.Fatalf("Open() = %v, want ErrLocked while the database is open", err)
+	}
+
+	if err := tui.Close(); err != nil {
+		t.Fatal(err)
+	}
+	cron, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cron.Close()
+	_, groups, err := cron.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Name != "News" {
+		t.Errorf("got groups %v, want the one stored by the first rfcui", groups)
+	}
+}
+
+func TestStarredAndSavedSearches(t *testing.T) {
+	s := openTestStore(t)
+
+	item := newTestFeed("1").Items[0]
+	searches := []*SavedSearch{{Title: "B", Query: "b"}, {Title: "A", Query: "a"}}
+	err := s.Update(func(tx Tx) error {
+		if err := tx.PutStarred(&StarredItem{Item: item, FeedTitle: "Blog"}); err != nil {
+			return err
+		}
+		if err := tx.PutSavedSearches([]*SavedSearch{{Title: "C", Query: "c"}}); err != nil {
+			return err
+		}
+		return tx.PutSavedSearches(searches)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	starred, err := s.LoadStarred()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starred) != 1 || starred[0].Item.ID != "1" || starred[0].FeedTitle != "Blog" {
+		t.Errorf("got starred items %v, want the one put", starred)
+	}
+	loaded, err := s.LoadSavedSearches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 2 || *loaded[0] != *searches[0] || *loaded[1] != *searches[1] {
+		t.Errorf("got saved searches %v, want the ones put last in their order", loaded)
+	}
+
+	if err := s.Update(func(tx Tx) error { return tx.DeleteStarred(item.Belong, item.ID) }); err != nil {
+		t.Fatal(err)
+	}
+	if starred, err := s.LoadStarred(); err != nil || len(starred) != 0 {
+		t.Errorf("got starred items %v and %v after deleting it, want none", starred, err)
+	}
+}
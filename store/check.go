@@ -0,0 +1,154 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/group"
+	"github.com/apxxxxxxe/rfcui/index"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// kinds of the records
+const (
+	KindFeed        = "feed"
+	KindGroup       = "group"
+	KindItem        = "item"
+	KindItemState   = "item state"
+	KindCacheFile   = "cache file"
+	KindStarred     = "starred item"
+	KindSavedSearch = "saved search"
+	KindIndexDoc    = "index doc"
+	KindStarredFile = "starred file"
+	KindSearchFile  = "saved searches file"
+)
+
+var quarantineBucket = []byte("quarantine")
+
+// Corruption is a broken record moved into quarantine.
+// Name is the feed link of a feed, an item, its state, its starred copy or its index doc, the ID of a group,
+// the position of a saved search or the path of a file.
+type Corruption struct {
+	Kind string
+	Name string
+	Err  error
+}
+
+func (c *Corruption) String() string {
+	return fmt.Sprintf("%s %s: %v", c.Kind, c.Name, c.Err)
+}
+
+// brokenRecord is a record which failed to decode.
+type brokenRecord struct {
+	// names of the bucket of the record and of the buckets it is in
+	bucket [][]byte
+	key    []byte
+	data   []byte
+	*Corruption
+}
+
+// Check decodes every record in a read-only transaction, each into a value of its own,
+// and opens a writable one only to move the broken records into quarantine.
+func (s *boltStore) Check() ([]*Corruption, error) {
+	broken := []brokenRecord{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		check := func(bucket [][]byte, kind string, name func(k []byte) string, newValue func() interface{}) error {
+			return lookup(tx, bucket).ForEach(func(k, data []byte) error {
+				if data == nil {
+					return nil
+				}
+				if err := decode(data, newValue()); err != nil {
+					broken = append(broken, brokenRecord{bucket, append([]byte{}, k...), append([]byte{}, data...),
+						&Corruption{Kind: kind, Name: name(k), Err: err}})
+				}
+				return nil
+			})
+		}
+		keyName := func(k []byte) string { return string(k) }
+		stateName := func(k []byte) string {
+			feedLink, _ := splitStateKey(k)
+			return feedLink
+		}
+
+		checks := []struct {
+			bucket   []byte
+			kind     string
+			name     func(k []byte) string
+			newValue func() interface{}
+		}{
+			{feedsBucket, KindFeed, keyName, func() interface{} { return &fd.Feed{} }},
+			{groupsBucket, KindGroup, keyName, func() interface{} { return &group.Group{} }},
+			{statesBucket, KindItemState, stateName, func() interface{} { return &itemState{} }},
+			{starredBucket, KindStarred, stateName, func() interface{} { return &StarredItem{} }},
+			{searchesBucket, KindSavedSearch, searchName, func() interface{} { return &SavedSearch{} }},
+			{indexBucket, KindIndexDoc, stateName, func() interface{} { return &index.Doc{} }},
+		}
+		for _, c := range checks {
+			if err := check([][]byte{c.bucket}, c.kind, c.name, c.newValue); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(itemsBucket).ForEach(func(feedLink, v []byte) error {
+			if v != nil {
+				return nil
+			}
+			bucket := [][]byte{itemsBucket, append([]byte{}, feedLink...)}
+			name := string(feedLink)
+			return check(bucket, KindItem, func([]byte) string { return name }, func() interface{} { return &fd.Item{} })
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(broken) == 0 {
+		return []*Corruption{}, nil
+	}
+
+	corruptions := []*Corruption{}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		t := &boltTx{tx: tx}
+		for _, r := range broken {
+			b := lookup(tx, r.bucket)
+			if !bytes.Equal(b.Get(r.key), r.data) {
+				// changed since it was checked
+				continue
+			}
+			if err := t.Quarantine(r.Kind, r.Name+"\x00"+string(r.key), r.data); err != nil {
+				return err
+			}
+			if err := b.Delete(r.key); err != nil {
+				return err
+			}
+			corruptions = append(corruptions, r.Corruption)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return corruptions, nil
+}
+
+// lookup returns the bucket of the names, each in the bucket of the name before.
+func lookup(tx *bolt.Tx, names [][]byte) *bolt.Bucket {
+	b := tx.Bucket(names[0])
+	for _, name := range names[1:] {
+		b = b.Bucket(name)
+	}
+	return b
+}
+
+func (t *boltTx) Quarantine(kind, key string, data []byte) error {
+	return t.tx.Bucket(quarantineBucket).Put([]byte(kind+"\x00"+key), data)
+}
+
+func splitStateKey(k []byte) (string, string) {
+	for i, c := range k {
+		if c == 0 {
+			return string(k[:i]), string(k[i+1:])
+		}
+	}
+	return string(k), ""
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"github.com/apxxxxxxe/rfcui/index"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var indexBucket = []byte("index")
+
+// LoadIndex returns the docs of the search index.
+func (s *boltStore) LoadIndex() ([]*index.Doc, error) {
+	docs := []*index.Doc{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(indexBucket).ForEach(func(k, v []byte) error {
+			doc := &index.Doc{}
+			if err := decode(v, doc); err != nil {
+				return errors.Wrap(err, string(k))
+			}
+			docs = append(docs, doc)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (t *boltTx) PutIndexDoc(doc *index.Doc) error {
+	return put(t.tx.Bucket(indexBucket), stateKey(doc.Belong, doc.ItemID), doc)
+}
+
+func (t *boltTx) DeleteIndexDoc(doc *index.Doc) error {
+	return t.tx.Bucket(indexBucket).Delete(stateKey(doc.Belong, doc.ItemID))
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/group"
+	myio "github.com/apxxxxxxe/rfcui/io"
+
+	"github.com/pkg/errors"
+)
+
+const migratedKey = "migrated_from_cache"
+
+var ErrNoFeedLink = errors.New("the feed has no feed link")
+
+// MigrateCache copies the feeds and groups cached in the files of cacheDir into s, once.
+// It returns the number of the files copied and the files which could not be read, which are put into quarantine.
+// The files are left as they are.
+func MigrateCache(s Store, cacheDir string) (int, []*Corruption, error) {
+	count := 0
+	corruptions := []*Corruption{}
+	err := s.Update(func(tx Tx) error {
+		if tx.Meta(migratedKey) != nil || !myio.IsDir(cacheDir) {
+			return nil
+		}
+
+		entries, err := os.ReadDir(cacheDir)
+		if err != nil {
+			return err
+		}
+		order := 0
+		for _, entry := range entries {
+			// the cache has a file for each feed or group and nothing else
+			if entry.IsDir() {
+				continue
+			}
+			file := filepath.Join(cacheDir, entry.Name())
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			f, err := fd.DecodeFeed(b)
+			if err == nil && len(f.FeedLinks) == 0 {
+				err = ErrNoFeedLink
+			}
+			if err != nil {
+				if err := tx.Quarantine(KindCacheFile, file, b); err != nil {
+					return err
+				}
+				corruptions = append(corruptions, &Corruption{Kind: KindCacheFile, Name: file, Err: err})
+				continue
+			}
+			if f.IsMerged() {
+				err = tx.PutGroup(group.FromFeed(f, order))
+				order++
+			} else {
+				err = tx.PutFeed(f)
+			}
+			if err != nil {
+				return errors.Wrap(err, file)
+			}
+			count++
+		}
+		return tx.PutMeta(migratedKey, []byte(time.Now().Format(time.RFC3339)))
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return count, corruptions, nil
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Version is the version of the schema of the records written by this build.
+// Bump it with a migration whenever the meaning of a record changes.
+const Version = 2
+
+var (
+	ErrBrokenRecord   = errors.New("broken record")
+	ErrNoHeader       = errors.New("record has no header")
+	ErrRecordTooNew   = errors.New("record is of a newer schema")
+	ErrDatabaseTooNew = errors.New("the database was written by a newer rfcui")
+)
+
+// every record starts with the magic and the version of its schema
+var magic = []byte("rfcui")
+
+func header(version byte) []byte {
+	return append(append([]byte{}, magic...), version)
+}
+
+func hasHeader(data []byte) bool {
+	return len(data) > len(magic) && bytes.HasPrefix(data, magic)
+}
+
+func encode(v interface{}) ([]byte, error) {
+	buf := bytes.NewBuffer(header(Version))
+	if err := gob.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decode(data []byte, v interface{}) error {
+	if !hasHeader(data) {
+		return fmt.Errorf("%w: %v", ErrBrokenRecord, ErrNoHeader)
+	}
+	if version := data[len(magic)]; version > Version {
+		return fmt.Errorf("%w: %v: %d", ErrBrokenRecord, ErrRecordTooNew, version)
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data[len(magic)+1:])).Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrBrokenRecord, err)
+	}
+	return nil
+}
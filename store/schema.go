@@ -0,0 +1,116 @@
+package store
+
+import (
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/group"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+const versionKey = "schema_version"
+
+// migration brings the records of the database from the version before to version.
+type migration struct {
+	version byte
+	migrate func(tx *bolt.Tx) error
+}
+
+var migrations = []migration{
+	{1, addHeaders},
+	{2, groupModel},
+}
+
+// migrateSchema runs the migrations the database has not had yet in one transaction,
+// and reports whether there were any.
+func migrateSchema(db *bolt.DB) (bool, error) {
+	migrated := false
+	err := db.Update(func(tx *bolt.Tx) error {
+		migrated = false
+		meta := tx.Bucket(metaBucket)
+		var version byte
+		if v := meta.Get([]byte(versionKey)); len(v) == 1 {
+			version = v[0]
+		}
+		if version > Version {
+			return errors.Wrapf(ErrDatabaseTooNew, "schema version %d", version)
+		}
+
+		for _, m := range migrations {
+			if m.version <= version {
+				continue
+			}
+			if err := m.migrate(tx); err != nil {
+				return errors.Wrapf(err, "migrating to schema version %d", m.version)
+			}
+			version = m.version
+			migrated = true
+		}
+		return meta.Put([]byte(versionKey), []byte{version})
+	})
+	return migrated, err
+}
+
+// groupModel turns the groups, which were merged feeds keyed by their titles, into groups keyed by their IDs.
+// The records which cannot be read are left to Check.
+func groupModel(tx *bolt.Tx) error {
+	b := tx.Bucket(groupsBucket)
+	titles := [][]byte{}
+	feeds := []*fd.Feed{}
+	err := b.ForEach(func(k, v []byte) error {
+		f := &fd.Feed{}
+		if err := decode(v, f); err == nil {
+			titles = append(titles, append([]byte{}, k...))
+			feeds = append(feeds, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the keys are the titles, so the groups keep the order they were shown in
+	for i, f := range feeds {
+		if err := b.Delete(titles[i]); err != nil {
+			return err
+		}
+		g := group.FromFeed(f, i)
+		if err := put(b, []byte(g.ID), g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addHeaders adds the header of version 1 to the records, which had none in version 0.
+func addHeaders(tx *bolt.Tx) error {
+	buckets := []*bolt.Bucket{tx.Bucket(feedsBucket), tx.Bucket(groupsBucket), tx.Bucket(statesBucket)}
+	err := tx.Bucket(itemsBucket).ForEach(func(k, v []byte) error {
+		if v == nil {
+			buckets = append(buckets, tx.Bucket(itemsBucket).Bucket(k))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, b := range buckets {
+		records := map[string][]byte{}
+		err := b.ForEach(func(k, v []byte) error {
+			if v != nil && !hasHeader(v) {
+				records[string(k)] = append(header(1), v...)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for k, v := range records {
+			if err := b.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/group"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// oldRecord encodes v as it was written by the schema of version, which had no header in version 0.
+func oldRecord(t *testing.T, version byte, v interface{}) []byte {
+	data, err := encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = data[len(magic)+1:]
+	if version == 0 {
+		return data
+	}
+	return append(header(version), data...)
+}
+
+// writeOldDatabase writes a feed with an item and a group, as the schema of version did.
+func writeOldDatabase(t *testing.T, path string, version byte) {
+	db, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		buckets := map[string]*bolt.Bucket{}
+		for _, name := range [][]byte{feedsBucket, groupsBucket, itemsBucket, statesBucket, metaBucket} {
+			b, err := tx.CreateBucket(name)
+			if err != nil {
+				return err
+			}
+			buckets[string(name)] = b
+		}
+		feed := &fd.Feed{Title: "Example", FeedLinks: []string{testFeedLink}}
+		if err := buckets["feeds"].Put([]byte(testFeedLink), oldRecord(t, version, feed)); err != nil {
+			return err
+		}
+		items, err := buckets["items"].CreateBucket([]byte(testFeedLink))
+		if err != nil {
+			return err
+		}
+		item := &fd.Item{ID: "1", Belong: testFeedLink, Title: "One"}
+		if err := items.Put([]byte("1"), oldRecord(t, version, item)); err != nil {
+			return err
+		}
+		if err := buckets["states"].Put(stateKey(testFeedLink, "1"), oldRecord(t, version, itemState{Read: true})); err != nil {
+			return err
+		}
+		// groups were merged feeds keyed by their titles
+		merged := &fd.Feed{Title: "News", Color: 3, FeedLinks: []string{testFeedLink, "https://example.org/feed"}}
+		if err := buckets["groups"].Put([]byte("News"), oldRecord(t, version, merged)); err != nil {
+			return err
+		}
+		if version == 0 {
+			return nil
+		}
+		return buckets["meta"].Put([]byte(versionKey), []byte{version})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// viewDatabase runs fn on the database at path, which no Store may hold open.
+func viewDatabase(t *testing.T, path string, fn func(tx *bolt.Tx) error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.View(fn); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateSchema(t *testing.T) {
+	for _, version := range []byte{0, 1} {
+		path := filepath.Join(t.TempDir(), FileName)
+		writeOldDatabase(t, path, version)
+
+		if s, err := OpenReadOnly(path); !errors.Is(err, ErrOldSchema) {
+			if err == nil {
+				s.Close()
+			}
+			t.Fatalf("version %d: OpenReadOnly() = %v, want ErrOldSchema", version, err)
+		}
+		s, err := Open(path)
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		feeds, groups, err := s.Load()
+		s.Close()
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if len(feeds) != 1 || feeds[0].Title != "Example" || len(feeds[0].Items) != 1 || !feeds[0].Items[0].Read {
+			t.Errorf("version %d: the feed or its read item was lost", version)
+		}
+		if len(groups) != 1 {
+			t.Fatalf("version %d: got %d groups, want 1", version, len(groups))
+		}
+		g := groups[0]
+		if g.Name != "News" || g.Options.Color != 3 || len(g.Members) != 2 || g.Members[0] != testFeedLink {
+			t.Errorf("version %d: got group %+v", version, g)
+		}
+
+		viewDatabase(t, path, func(tx *bolt.Tx) error {
+			if v := tx.Bucket(metaBucket).Get([]byte(versionKey)); !bytes.Equal(v, []byte{Version}) {
+				t.Errorf("version %d: schema version is %v, want %d", version, v, Version)
+			}
+			if tx.Bucket(groupsBucket).Get([]byte(g.ID)) == nil || tx.Bucket(groupsBucket).Get([]byte("News")) != nil {
+				t.Errorf("version %d: the group is not keyed by its ID", version)
+			}
+			for _, data := range [][]byte{
+				tx.Bucket(feedsBucket).Get([]byte(testFeedLink)),
+				tx.Bucket(itemsBucket).Bucket([]byte(testFeedLink)).Get([]byte("1")),
+				tx.Bucket(statesBucket).Get(stateKey(testFeedLink, "1")),
+			} {
+				if !hasHeader(data) {
+					t.Errorf("version %d: a record has no header", version)
+				}
+			}
+			return nil
+		})
+	}
+}
+
+func TestCheckQuarantinesBrokenRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Update(func(tx Tx) error {
+		if err := tx.PutFeed(newTestFeed("1")); err != nil {
+			return err
+		}
+		return tx.PutGroup(group.New("News", []string{testFeedLink}, 0))
+	})
+	s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	broken := append(header(Version), "broken"...)
+	db, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(feedsBucket).Put([]byte(testFeedLink), broken)
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corruptions, err := s.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corruptions) != 1 || corruptions[0].Kind != KindFeed || corruptions[0].Name != testFeedLink {
+		t.Fatalf("got corruptions %v, want the feed", corruptions)
+	}
+	feeds, groups, err := s.Load()
+	s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 0 || len(groups) != 1 {
+		t.Errorf("got %d feeds and %d groups, want 0 and 1", len(feeds), len(groups))
+	}
+
+	viewDatabase(t, path, func(tx *bolt.Tx) error {
+		key := KindFeed + "\x00" + testFeedLink + "\x00" + testFeedLink
+		if v := tx.Bucket(quarantineBucket).Get([]byte(key)); !bytes.Equal(v, broken) {
+			t.Errorf("the broken feed is not in quarantine")
+		}
+		return nil
+	})
+}
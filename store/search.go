@@ -0,0 +1,67 @@
+package store
+
+import (
+	"encoding/binary"
+	"strconv"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var searchesBucket = []byte("searches")
+
+// SavedSearch is a query shown as a group.
+type SavedSearch struct {
+	Title string
+	Query string
+}
+
+// LoadSavedSearches returns the saved searches in the order they were put.
+func (s *boltStore) LoadSavedSearches() ([]*SavedSearch, error) {
+	searches := []*SavedSearch{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(searchesBucket).ForEach(func(k, v []byte) error {
+			record := &SavedSearch{}
+			if err := decode(v, record); err != nil {
+				return errors.Wrap(err, searchName(k))
+			}
+			searches = append(searches, record)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return searches, nil
+}
+
+// PutSavedSearches replaces the saved searches with searches.
+func (t *boltTx) PutSavedSearches(searches []*SavedSearch) error {
+	if err := t.tx.DeleteBucket(searchesBucket); err != nil {
+		return err
+	}
+	b, err := t.tx.CreateBucket(searchesBucket)
+	if err != nil {
+		return err
+	}
+	for i, s := range searches {
+		if err := put(b, searchKey(i), s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchKey is the position of a saved search, big-endian so that the keys sort in order.
+func searchKey(i int) []byte {
+	k := make([]byte, 4)
+	binary.BigEndian.PutUint32(k, uint32(i))
+	return k
+}
+
+func searchName(k []byte) string {
+	if len(k) != 4 {
+		return string(k)
+	}
+	return "#" + strconv.Itoa(int(binary.BigEndian.Uint32(k)))
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	fd "github.com/apxxxxxxe/rfcui/feed"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var starredBucket = []byte("starred")
+
+// StarredItem is the copy of a starred item, kept after the item has left its feed.
+// The title of the feed is kept with it, as the feed may be gone too.
+type StarredItem struct {
+	Item      *fd.Item
+	FeedTitle string
+}
+
+func (s *boltStore) LoadStarred() ([]*StarredItem, error) {
+	starred := []*StarredItem{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(starredBucket).ForEach(func(k, v []byte) error {
+			record := &StarredItem{}
+			if err := decode(v, record); err != nil {
+				return errors.Wrap(err, string(k))
+			}
+			starred = append(starred, record)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return starred, nil
+}
+
+func (t *boltTx) PutStarred(s *StarredItem) error {
+	return put(t.tx.Bucket(starredBucket), stateKey(s.Item.Belong, s.Item.ID), s)
+}
+
+func (t *boltTx) DeleteStarred(belong, id string) error {
+	return t.tx.Bucket(starredBucket).Delete(stateKey(belong, id))
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	fd "github.com/apxxxxxxe/rfcui/feed"
+	"github.com/apxxxxxxe/rfcui/group"
+	"github.com/apxxxxxxe/rfcui/index"
+)
+
+// Store keeps the feeds, the groups, the items of the feeds and the state of the items.
+type Store interface {
+	// Load returns the feeds with their items, and the groups.
+	Load() ([]*fd.Feed, []*group.Group, error)
+	// Update runs fn in a transaction; nothing is stored if fn returns an error.
+	Update(fn func(Tx) error) error
+	// LoadStarred returns the copies of the starred items.
+	LoadStarred() ([]*StarredItem, error)
+	LoadSavedSearches() ([]*SavedSearch, error)
+	// LoadIndex returns the docs of the search index, from which the index is made again.
+	LoadIndex() ([]*index.Doc, error)
+	// Check moves the records which cannot be read into quarantine and reports them.
+	Check() ([]*Corruption, error)
+	// Migrated reports whether the schema was migrated when the store was opened.
+	Migrated() bool
+	Close() error
+}
+
+// Tx changes a Store as a whole.
+type Tx interface {
+	// PutFeed stores f with its items, replacing the stored ones.
+	PutFeed(f *fd.Feed) error
+	DeleteFeed(feedLink string) error
+	PutGroup(g *group.Group) error
+	DeleteGroup(id string) error
+	// PutItemState stores whether item is read or starred, without storing the rest of its feed.
+	PutItemState(item *fd.Item) error
+	// PutStarred stores the copy of a starred item, which outlives the item in its feed.
+	PutStarred(s *StarredItem) error
+	DeleteStarred(belong, id string) error
+	PutSavedSearches(searches []*SavedSearch) error
+	PutIndexDoc(doc *index.Doc) error
+	DeleteIndexDoc(doc *index.Doc) error
+	Meta(key string) []byte
+	PutMeta(key string, value []byte) error
+	// Quarantine keeps broken data out of the way, to be recovered by hand.
+	Quarantine(kind, key string, data []byte) error
+}
+
+// itemState is the part of an item changed by the user.
+type itemState struct {
+	Read    bool
+	Starred bool
+}
+
+func stateOf(item *fd.Item) itemState {
+	return itemState{Read: item.Read, Starred: item.Starred}
+}
+
+func (s itemState) applyTo(item *fd.Item) {
+	item.Read = s.Read
+	item.Starred = s.Starred
+}
@@ -1,125 +1,48 @@
 package tui
 
 import (
-	"crypto/md5"
-	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
-	"sort"
-	"strings"
-
 	mycolor "github.com/apxxxxxxe/rfcui/color"
-	fd "github.com/apxxxxxxe/rfcui/feed"
-	myio "github.com/apxxxxxxe/rfcui/io"
+	"github.com/apxxxxxxe/rfcui/core"
 
 	"github.com/rivo/tview"
 )
 
 type GroupWidget struct {
-	Table  *tview.Table
-	Groups []*fd.Feed
-}
-
-func (m *GroupWidget) SaveGroup(f *fd.Feed) error {
-	b, err := fd.EncodeFeed(f)
-	if err != nil {
-		return err
-	}
-
-	hash := fmt.Sprintf("%x", md5.Sum([]byte(f.Title)))
-
-	if err := myio.SaveBytes(b, filepath.Join(cachePath, hash)); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (m *GroupWidget) SaveFeeds() error {
-	for _, f := range m.Groups {
-		if err := m.SaveGroup(f); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func (m *GroupWidget) LoadFeeds(path string) error {
-	for _, file := range myio.DirWalk(path) {
-		b, err := ioutil.ReadFile(file)
-		if err != nil {
-			return err
-		}
-		m.Groups = append(m.Groups, fd.DecodeFeed(b))
-	}
-	return nil
+	Table   *tview.Table
+	Manager *core.Manager
 }
 
 func (m *GroupWidget) DeleteSelection() error {
 	row, _ := m.Table.GetSelection()
-	if err := m.DeleteFeedFile(row); err != nil {
-		return err
-	}
-	m.DeleteFeed(row)
-	return nil
-}
-
-func (m *GroupWidget) DeleteFeedFile(index int) error {
-	var hash string
-
-	v := m.Groups[index]
-
-	if v.IsMerged() {
-		hash = fmt.Sprintf("%x", md5.Sum([]byte(v.Title)))
-	} else {
-
-		feedLink, _ := v.GetFeedLink()
-		hash = fmt.Sprintf("%x", md5.Sum([]byte(feedLink)))
-	}
-
-	if err := os.Remove(filepath.Join(cachePath, hash)); err != nil {
-		return ErrRmFailed
-	}
-	return nil
-}
-
-func (m *GroupWidget) DeleteFeed(i int) {
-	m.Groups = append(m.Groups[:i], m.Groups[i+1:]...)
-}
-
-func (m *GroupWidget) sortFeeds() {
-	sort.Slice(m.Groups, func(i, j int) bool {
-		return strings.Compare(m.Groups[i].Title, m.Groups[j].Title) == -1
-	})
-	sort.Slice(m.Groups, func(i, j int) bool {
-		return m.Groups[i].IsMerged() && !m.Groups[j].IsMerged()
-	})
+	return m.Manager.DeleteGroup(m.Manager.Groups[row])
 }
 
-func (m *GroupWidget) AddMergedFeed(feeds []*fd.Feed, title string) error {
-	f, err := fd.MergeFeeds(feeds, title)
-	if err != nil {
-		return err
-	}
-	m.Groups = append(m.Groups, f)
-	if err := m.SaveGroup(f); err != nil {
-		return err
+// MoveSelection moves the selecting group by delta places and keeps it selected.
+func (m *GroupWidget) MoveSelection(delta int) (bool, error) {
+	row, _ := m.Table.GetSelection()
+	title := m.Manager.Groups[row].Title
+	moved, err := m.Manager.MoveGroup(m.Manager.Groups[row], delta)
+	if err != nil || !moved {
+		return false, err
+	}
+	m.setGroups()
+	for i, g := range m.Manager.Groups {
+		if g.Title == title {
+			m.Table.Select(i, 0)
+		}
 	}
-	return nil
+	return true, nil
 }
 
 func (m *GroupWidget) setGroups() {
-	m.sortFeeds()
+	m.Manager.SortGroups()
 	table := m.Table.Clear()
-	for i, feed := range m.Groups {
-		table.SetCellSimple(i, 0, feed.Title)
-		if !feed.IsMerged() {
-			if feed.Color < 0 || feed.Color > len(mycolor.TcellColors) {
-				table.GetCell(i, 0).SetTextColor(mycolor.TcellColors[15])
-			} else {
-				table.GetCell(i, 0).SetTextColor(mycolor.TcellColors[feed.Color])
-			}
+	for i, feed := range m.Manager.Groups {
+		table.SetCellSimple(i, 0, feedTitleWithUnread(feed))
+		if feed.Color < 0 || feed.Color > len(mycolor.TcellColors) {
+			table.GetCell(i, 0).SetTextColor(mycolor.TcellColors[15])
+		} else {
+			table.GetCell(i, 0).SetTextColor(mycolor.TcellColors[feed.Color])
 		}
 	}
 	row, _ := m.Table.GetSelection()
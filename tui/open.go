@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/apxxxxxxe/rfcui/opener"
+
+	"github.com/pkg/errors"
+)
+
+var ErrNoBrowser = errors.New("no browser is configured; set browser in the config or $BROWSER")
+
+func (tui *Tui) browser() string {
+	if tui.Config.Browser != "" {
+		return tui.Config.Browser
+	}
+	return os.Getenv("BROWSER")
+}
+
+// open opens t with the first opener matching it, or with the browser.
+// A foreground command suspends the TUI until it exits;
+// the failure of a background one is notified when it exits.
+func (tui *Tui) open(t opener.Target) error {
+	rule := opener.Find(tui.Config.Openers, t)
+	if rule == nil {
+		browser := tui.browser()
+		if browser == "" {
+			return ErrNoBrowser
+		}
+		var err error
+		tui.App.Suspend(func() {
+			err = execCmd(true, browser, t.URL)
+		})
+		return errors.Wrap(err, browser)
+	}
+
+	command := rule.Expand(t)
+	cmd := exec.Command("sh", "-c", command)
+	if !rule.Background {
+		var err error
+		tui.App.Suspend(func() {
+			cmd.Stdin = os.Stdin
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			err = cmd.Run()
+		})
+		return errors.Wrap(err, command)
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, command)
+	}
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			message := fmt.Sprint(command, ": ", err)
+			if s := strings.TrimSpace(stderr.String()); s != "" {
+				message += ": " + s
+			}
+			tui.App.QueueUpdateDraw(func() {
+				tui.NotifyError(message)
+			})
+		}
+	}()
+	return nil
+}
+
+// feedTitle returns the title of the feed whose feed link is belong, or "" if it is not found.
+func (tui *Tui) feedTitle(belong string) string {
+	return tui.Manager.FeedTitle(belong)
+}
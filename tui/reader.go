@@ -0,0 +1,158 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/apxxxxxxe/rfcui/opener"
+	"github.com/apxxxxxxe/rfcui/render"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/pkg/errors"
+	"github.com/rivo/tview"
+)
+
+var (
+	ErrNoSuchLink   = errors.New("no such link")
+	ErrNoClipboard  = errors.New("no clipboard command is found; set clipboard in the config")
+	ErrEmptyCommand = errors.New("command is empty")
+)
+
+// commands tried in order to copy to the clipboard
+var clipboardCommands = []string{
+	"wl-copy",
+	"xclip -selection clipboard",
+	"xsel --clipboard --input",
+	"pbcopy",
+	"clip.exe",
+}
+
+// readItem shows the content of the item at row in the reader and marks it as read.
+func (tui *Tui) readItem(row int) {
+	if len(tui.SubWidget.Items) == 0 {
+		return
+	}
+	item := tui.SubWidget.Items[row]
+
+	feedTitle := tui.feedTitle(item.Belong)
+	header := fmt.Sprint(
+		"[::b]", tview.Escape(item.Title), "[::-]\n",
+		"[#a0a0a0]", tview.Escape(feedTitle), " / ", item.FormatDateAs(tui.Config.Dates.Description), "[-]\n",
+		"[#a0a0a0]", tview.Escape(item.Link), "[-]\n",
+	)
+	page := render.HTML(item.Body(), item.Link)
+	// the enclosures are numbered after the links in the content
+	for _, e := range item.Enclosures {
+		page.Links = append(page.Links, render.Link{URL: e.URL, Text: item.Title, Type: e.Type})
+		reference := tview.Escape("[" + strconv.Itoa(len(page.Links)) + "]")
+		header += fmt.Sprint("[#a0a0a0]Enclosure ", reference, ": ", tview.Escape(formatEnclosure(e)), "[-]\n")
+	}
+	header += "\n"
+	tui.ReaderItem = item
+	tui.ReaderLinks = page.Links
+
+	tui.Reader.SetTitle(item.Title)
+	tui.Reader.SetText(header + page.WithReferences())
+	tui.Reader.ScrollToBeginning()
+	tui.Pages.SwitchToPage(readerPage)
+	tui.App.SetFocus(tui.Reader)
+
+	if err := tui.Manager.MarkRead(item); err != nil {
+		tui.NotifyError(err.Error())
+		return
+	}
+	tui.SubWidget.Table.GetCell(row, 0).SetAttributes(tcell.AttrNone)
+}
+
+func (tui *Tui) closeReader() {
+	tui.Pages.SwitchToPage(mainPage)
+	tui.App.SetFocus(tui.SubWidget.Table)
+	tui.RefreshTui()
+}
+
+// readerLink returns the link of the reader numbered as text.
+func (tui *Tui) readerLink(text string) (render.Link, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || n < 1 || n > len(tui.ReaderLinks) {
+		return render.Link{}, errors.Wrap(ErrNoSuchLink, text)
+	}
+	return tui.ReaderLinks[n-1], nil
+}
+
+func (tui *Tui) openLink(text string) error {
+	link, err := tui.readerLink(text)
+	if err != nil {
+		return err
+	}
+	return tui.open(opener.Target{
+		URL:   link.URL,
+		Title: link.Text,
+		Feed:  tui.feedTitle(tui.ReaderItem.Belong),
+		MIME:  link.Type,
+	})
+}
+
+func (tui *Tui) copyLink(text string) error {
+	link, err := tui.readerLink(text)
+	if err != nil {
+		return err
+	}
+
+	command := tui.Config.Clipboard
+	if command == "" {
+		for _, c := range clipboardCommands {
+			if _, err := exec.LookPath(strings.Fields(c)[0]); err == nil {
+				command = c
+				break
+			}
+		}
+	}
+	if command == "" {
+		return ErrNoClipboard
+	}
+
+	args := strings.Fields(command)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(link.URL)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrap(err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// pipeLink runs the command in text like "3 less" with the URL of the link on its standard input.
+// The TUI is suspended while it runs.
+func (tui *Tui) pipeLink(text string) error {
+	fields := strings.SplitN(strings.TrimSpace(text), " ", 2)
+	link, err := tui.readerLink(fields[0])
+	if err != nil {
+		return err
+	}
+	if len(fields) < 2 || strings.TrimSpace(fields[1]) == "" {
+		return ErrEmptyCommand
+	}
+
+	cmd := exec.Command("sh", "-c", fields[1])
+	cmd.Stdin = strings.NewReader(link.URL + "\n")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	tui.App.Suspend(func() {
+		err = cmd.Run()
+	})
+	return err
+}
+
+func (tui *Tui) showLinkInput(title string, mode int, hint string) {
+	if len(tui.ReaderLinks) == 0 {
+		tui.Notify("This item has no links.")
+		return
+	}
+	tui.InputWidget.Input.SetTitle(title)
+	tui.InputWidget.Mode = mode
+	tui.Pages.ShowPage(inputField)
+	tui.App.SetFocus(tui.InputWidget.Input)
+	tui.Notify(hint)
+}
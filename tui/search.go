@@ -0,0 +1,191 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/apxxxxxxe/rfcui/query"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	searchMode       = 9
+	globalSearchMode = 10
+	saveSearchMode   = 11
+	matchColor       = tcell.ColorOlive
+)
+
+func (tui *Tui) startSearch() {
+	tui.searchOrigin, _ = tui.SubWidget.Table.GetSelection()
+	tui.InputWidget.Input.SetTitle("search")
+	tui.InputWidget.Mode = searchMode
+	tui.Pages.ShowPage(inputField)
+	tui.App.SetFocus(tui.InputWidget.Input)
+	tui.Notify(`Search like: word "two words" feed:name title:regexp author:name category:name since:week unread:yes starred:yes`)
+}
+
+// search highlights the items matching text as it is typed,
+// selecting the first match from where the search started.
+func (tui *Tui) search(text string) {
+	q, err := query.Parse(text)
+	if err != nil {
+		tui.NotifyError(err.Error())
+		return
+	}
+	if q.IsEmpty() {
+		tui.clearSearch()
+		tui.SubWidget.Table.Select(tui.searchOrigin, 0)
+		return
+	}
+
+	tui.Search = q
+	tui.highlightMatches()
+	if len(tui.Matches) == 0 {
+		tui.Notify("No matches.")
+		return
+	}
+	tui.SubWidget.Table.Select(tui.nextMatch(tui.searchOrigin-1, true), 0)
+	tui.Notify(fmt.Sprint(len(tui.Matches), " matches"))
+}
+
+// finishSearch closes the input, keeping the matches unless canceled.
+func (tui *Tui) finishSearch(canceled bool) {
+	// keeps SetText from searching again
+	tui.InputWidget.Mode = 0
+	tui.InputWidget.Input.SetText("")
+	tui.InputWidget.Input.SetTitle("Input")
+	tui.Pages.HidePage(inputField)
+	tui.App.SetFocus(tui.SubWidget.Table)
+
+	if canceled {
+		tui.clearSearch()
+		tui.SubWidget.Table.Select(tui.searchOrigin, 0)
+		tui.Notify("")
+		return
+	}
+	if tui.Search != nil {
+		tui.Notify(fmt.Sprint(len(tui.Matches), " matches. n/N: next/previous match, w: save as a group, Esc: clear"))
+	}
+}
+
+func (tui *Tui) clearSearch() {
+	tui.Search = nil
+	tui.highlightMatches()
+}
+
+// highlightMatches finds the items matching the search and paints their rows.
+func (tui *Tui) highlightMatches() {
+	tui.Matches = []int{}
+	titles := map[string]string{}
+	if tui.Search != nil {
+		for _, f := range tui.Manager.Feeds {
+			feedLink, _ := f.GetFeedLink()
+			titles[feedLink] = f.Title
+		}
+	}
+
+	for i, item := range tui.SubWidget.Items {
+		cell := tui.SubWidget.Table.GetCell(i, 0)
+		if tui.Search != nil && tui.Search.Match(item, titles[item.Belong]) {
+			tui.Matches = append(tui.Matches, i)
+			cell.SetBackgroundColor(matchColor)
+		} else {
+			cell.SetTransparency(true)
+		}
+	}
+}
+
+// jumpToMatch selects the next match after the selected row, or the previous one before it.
+func (tui *Tui) jumpToMatch(forward bool) {
+	if len(tui.Matches) == 0 {
+		tui.Notify("No matches.")
+		return
+	}
+	row, _ := tui.SubWidget.Table.GetSelection()
+	next := tui.nextMatch(row, forward)
+	tui.SubWidget.Table.Select(next, 0)
+
+	for i, match := range tui.Matches {
+		if match == next {
+			tui.Notify(fmt.Sprint("match ", i+1, "/", len(tui.Matches)))
+		}
+	}
+}
+
+// nextMatch returns the first match after row, or before it if not forward, wrapping around.
+func (tui *Tui) nextMatch(row int, forward bool) int {
+	if forward {
+		for _, match := range tui.Matches {
+			if match > row {
+				return match
+			}
+		}
+		return tui.Matches[0]
+	}
+	for i := len(tui.Matches) - 1; i >= 0; i-- {
+		if tui.Matches[i] < row {
+			return tui.Matches[i]
+		}
+	}
+	return tui.Matches[len(tui.Matches)-1]
+}
+
+// isSearching reports whether the keys of the search are to be given to the items.
+func (tui *Tui) isSearching() bool {
+	return tui.Search != nil && tui.App.GetFocus() == tui.SubWidget.Table
+}
+
+func (tui *Tui) startSaveSearch() {
+	if tui.Search == nil {
+		tui.Notify("Search items with / first.")
+		return
+	}
+	tui.InputWidget.Input.SetTitle("name the group of " + tui.Search.Source)
+	tui.InputWidget.Mode = saveSearchMode
+	tui.Pages.ShowPage(inputField)
+	tui.App.SetFocus(tui.InputWidget.Input)
+	tui.Notify("The group shows the items of all feeds matching the search.")
+}
+
+// saveSearch saves the current search as a group of the title.
+func (tui *Tui) saveSearch(title string) {
+	if title == "" {
+		tui.NotifyError("empty title")
+		return
+	}
+	if _, err := tui.Manager.AddSavedSearch(title, tui.Search.Source); err != nil {
+		tui.NotifyError(err.Error())
+		return
+	}
+	tui.Notify("Saved.")
+}
+
+func (tui *Tui) startGlobalSearch() {
+	if tui.App.GetFocus() == tui.GroupWidget.Table {
+		tui.LastSelectedWidget = tui.GroupWidget.Table
+	} else {
+		tui.LastSelectedWidget = tui.FeedWidget.Table
+	}
+	tui.InputWidget.Input.SetTitle("search all items")
+	tui.InputWidget.Mode = globalSearchMode
+	tui.Pages.ShowPage(inputField)
+	tui.App.SetFocus(tui.InputWidget.Input)
+	tui.Notify("Enter words to search the items of all feeds, archived ones included.")
+}
+
+// showSearchResults lists the items of all feeds matching text in the items pane.
+func (tui *Tui) showSearchResults(text string) {
+	items := tui.Manager.Search(text)
+	if len(items) == 0 {
+		tui.Notify("No items found.")
+		return
+	}
+
+	tui.clearSearch()
+	tui.renderItems(items, true)
+	tui.SubWidget.Table.SetTitle(fmt.Sprintf("Results of %s (%d)", tview.Escape(text), len(items)))
+	tui.SubWidget.Table.Select(0, 0).ScrollToBeginning()
+	tui.App.SetFocus(tui.SubWidget.Table)
+	tui.RefreshTui()
+}
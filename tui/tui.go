@@ -1,23 +1,26 @@
 package tui
 
 import (
-	"crypto/md5"
-	"errors"
 	"fmt"
-	"io/ioutil"
-	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/apxxxxxxe/rfcui/cache"
 	mycolor "github.com/apxxxxxxe/rfcui/color"
+	"github.com/apxxxxxxe/rfcui/config"
+	"github.com/apxxxxxxe/rfcui/core"
 	fd "github.com/apxxxxxxe/rfcui/feed"
-	myio "github.com/apxxxxxxe/rfcui/io"
+	"github.com/apxxxxxxe/rfcui/opener"
+	"github.com/apxxxxxxe/rfcui/query"
+	"github.com/apxxxxxxe/rfcui/render"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
 	"github.com/rivo/tview"
 )
 
@@ -26,20 +29,22 @@ const (
 	descriptionPage           = "descriptionPage"
 	mainPage                  = "MainPage"
 	modalPage                 = "modalPage"
+	readerPage                = "readerPage"
+	downloadedMark            = "↓ "
+	starredMark               = "★ "
 	defaultConfirmationStatus = '0'
-	selectingColor            = tcell.ColorGreen
 	groupWidgetTitle          = "Groups"
 	FeedWidgetTitle           = "Feeds"
+	statusTimeLayout          = "2006/01/02 15:04"
 	subWidgetTitle            = "Items"
+	eventQueueSize            = 256
 )
 
-var (
-	ErrGettingFeedFailed = errors.New("failed to get feed")
-	ErrRmFailed          = errors.New("faled to remove files or dirs")
-	cachePath            = filepath.Join(getDataPath(), "cache")
-	exportListPath       = filepath.Join(getDataPath(), "list_export.txt")
-	importListPath       = filepath.Join(getDataPath(), "list.txt")
-)
+// the files of the imports and exports, in the data directory set by the config
+func exportListPath() string { return filepath.Join(cache.DataPath, "list_export.txt") }
+func importListPath() string { return filepath.Join(cache.DataPath, "list.txt") }
+func exportOPMLPath() string { return filepath.Join(cache.DataPath, "export.opml") }
+func importOPMLPath() string { return filepath.Join(cache.DataPath, "import.opml") }
 
 type Tui struct {
 	App                *tview.Application
@@ -56,6 +61,19 @@ type Tui struct {
 	ConfirmationStatus rune
 	LastSelectedWidget tview.Primitive
 	Modal              *tview.Modal
+	Reader             *tview.TextView
+	ReaderItem         *fd.Item
+	ReaderLinks        []render.Link
+	Search             *query.Query
+	Matches            []int
+	ShowHidden         bool
+	Manager            *core.Manager
+	Config             *config.Config
+	events             chan core.Event
+	// closed when the app has stopped
+	stopped chan struct{}
+	// the row selected when the search started
+	searchOrigin int
 }
 
 func (tui *Tui) SelectFeed() {
@@ -65,10 +83,10 @@ func (tui *Tui) SelectFeed() {
 	row, column := tui.FeedWidget.Table.GetSelection()
 	if tui.FeedWidget.Table.GetCell(row, column).BackgroundColor == defaultColor {
 		tui.FeedWidget.Table.GetCell(row, column).SetBackgroundColor(selectedColor)
-		tui.SelectingFeeds = append(tui.SelectingFeeds, tui.FeedWidget.Feeds[row])
+		tui.SelectingFeeds = append(tui.SelectingFeeds, tui.Manager.Feeds[row])
 	} else {
 		tui.FeedWidget.Table.GetCell(tui.FeedWidget.Table.GetSelection()).SetBackgroundColor(defaultColor)
-		targetFeed := tui.FeedWidget.Feeds[row]
+		targetFeed := tui.Manager.Feeds[row]
 		for i, f := range tui.SelectingFeeds {
 			if f == targetFeed {
 				tui.SelectingFeeds = append(tui.SelectingFeeds[:i], tui.SelectingFeeds[i+1:]...)
@@ -78,95 +96,6 @@ func (tui *Tui) SelectFeed() {
 	}
 }
 
-func (tui *Tui) updateGroup(index int) error {
-	targetFeed := tui.GroupWidget.Groups[index]
-	tui.GroupWidget.Groups[index].Items = []*fd.Item{}
-	for _, url := range targetFeed.FeedLinks {
-		for _, f := range tui.FeedWidget.Feeds {
-			feedLink, _ := f.GetFeedLink()
-			if url == feedLink {
-				tui.GroupWidget.Groups[index].Items = append(tui.GroupWidget.Groups[index].Items, f.Items...)
-				break
-			}
-		}
-	}
-	tui.GroupWidget.Groups[index].SortItems()
-	return nil
-}
-
-func (tui *Tui) updateFeed(index int) error {
-	targetFeed := tui.FeedWidget.Feeds[index]
-
-	color := targetFeed.Color
-	url, err := targetFeed.GetFeedLink()
-	if err != nil {
-		return fmt.Errorf(targetFeed.Title, ": ", err)
-	}
-
-	feed, err := fd.GetFeedFromURL(url, "")
-
-	if err != nil {
-		feed = getInvalidFeed(url, err)
-	}
-
-	if color > 0 && color < len(mycolor.TcellColors) {
-		for _, item := range feed.Items {
-			item.Color = targetFeed.Color
-		}
-	} else {
-		targetFeed.Title = feed.Title
-		targetFeed.Color = feed.Color
-	}
-	feed.SortItems()
-
-	targetFeed.Link = feed.Link
-	targetFeed.Description = feed.Description
-	targetFeed.Items = feed.Items
-
-	if err != nil {
-		return ErrGettingFeedFailed
-	}
-	return nil
-}
-
-func (tui *Tui) AddFeedFromURL(url string) error {
-	f, err := fd.GetFeedFromURL(url, "")
-	if err != nil {
-		return err
-	}
-
-	if f.IsMerged() {
-		if err := tui.GroupWidget.SaveGroup(f); err != nil {
-			return err
-		}
-	} else {
-		if err := tui.FeedWidget.SaveFeed(f); err != nil {
-			return err
-		}
-	}
-
-	for i, feed := range tui.FeedWidget.Feeds {
-		if f.IsMerged() {
-			if f.Title == feed.Title {
-				tui.FeedWidget.Feeds[i] = f
-				tui.FeedWidget.setFeeds()
-				return nil
-			}
-		} else {
-			feedLink, _ := feed.GetFeedLink()
-			if feedLink == url {
-				tui.FeedWidget.Feeds[i] = f
-				tui.FeedWidget.setFeeds()
-				return nil
-			}
-		}
-	}
-	tui.FeedWidget.Feeds = append(tui.FeedWidget.Feeds, f)
-	tui.FeedWidget.setFeeds()
-	return nil
-
-}
-
 func (tui *Tui) LoadCells(table *tview.Table, texts []string) {
 	table.Clear()
 	for i, text := range texts {
@@ -174,12 +103,6 @@ func (tui *Tui) LoadCells(table *tview.Table, texts []string) {
 	}
 }
 
-func getDataPath() string {
-	const dataRoot = "rfcui"
-	configDir, _ := os.UserConfigDir()
-	return filepath.Join(configDir, dataRoot)
-}
-
 func (tui *Tui) showDescription(texts [][]string) {
 	var s string
 	for _, line := range texts {
@@ -222,7 +145,7 @@ func (tui *Tui) RefreshTui() {
 	}
 	for _, table := range tables {
 		if focus == table {
-			table.SetBorderColor(selectingColor)
+			table.SetBorderColor(tui.Config.SelectingColor())
 		} else {
 			table.SetBorderColor(tcell.ColorDefault)
 		}
@@ -239,21 +162,14 @@ func (tui *Tui) setItems(paintColor, resetRow bool) {
 	focus := tui.App.GetFocus()
 	if focus == tui.GroupWidget.Table {
 		row, _ = tui.GroupWidget.Table.GetSelection()
-		items = tui.GroupWidget.Groups[row].Items
+		items = tui.Manager.GroupItems(tui.Manager.Groups[row])
 	} else if focus == tui.FeedWidget.Table {
 		row, _ = tui.FeedWidget.Table.GetSelection()
-		items = tui.FeedWidget.Feeds[row].Items
+		items = tui.Manager.Feeds[row].Items
 	}
 
-	tui.SubWidget.Items = items
-
-	table := tui.SubWidget.Table.Clear()
-	for i, item := range items {
-		table.SetCellSimple(i, 0, item.Title)
-		if paintColor && item.Color > 0 && item.Color < len(mycolor.TcellColors) {
-			table.GetCell(i, 0).SetTextColor(mycolor.TcellColors[item.Color])
-		}
-	}
+	tui.SubWidget.Table.SetTitle(subWidgetTitle)
+	tui.renderItems(items, paintColor)
 
 	if tui.SubWidget.Table.GetRowCount() != 0 {
 		if resetRow {
@@ -270,111 +186,126 @@ func (tui *Tui) setItems(paintColor, resetRow bool) {
 	}
 }
 
-func (tui *Tui) GetTodaysFeeds() error {
-	const feedname = "Today's Items"
-
-	targetfeed, err := fd.MergeFeeds(tui.FeedWidget.Feeds, feedname)
-	if err != nil {
-		return err
-	}
+func (tui *Tui) updateAllFeed() error {
+	return tui.Manager.UpdateAll()
+}
 
-	// 現在時刻より未来のフィードを除外
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	result := make([]*fd.Item, 0)
-	for _, item := range targetfeed.Items {
-		if today.Before(item.PubDate) {
-			result = append(result, item)
-		}
-	}
-	targetfeed.Items = result
-
-	isExist := false
-	for i, g := range tui.GroupWidget.Groups {
-		if g.Title == feedname {
-			tui.GroupWidget.Groups[i] = targetfeed
-			isExist = true
-			break
+// updateAllFeedAsync runs updateAllFeed in the background so that the app keeps responding.
+func (tui *Tui) updateAllFeedAsync() {
+	tui.WaitGroup.Add(1)
+	go func() {
+		defer tui.WaitGroup.Done()
+		if err := tui.updateAllFeed(); err != nil {
+			tui.handleEvent(core.Event{Type: core.UpdateFinished, Err: err})
 		}
-	}
-	if !isExist {
-		tui.GroupWidget.Groups = append(tui.GroupWidget.Groups, targetfeed)
-	}
-	tui.GroupWidget.setGroups()
-	return nil
+	}()
 }
 
-func getInvalidFeed(url string, err error) *fd.Feed {
-	return &fd.Feed{
-		Title:       "failed to retrieve: " + url,
-		Color:       1, // Red
-		Description: fmt.Sprint("Failed to retrieve feed:\n", err),
-		Link:        "",
-		FeedLinks:   []string{url},
-		Items:       []*fd.Item{},
+// handleEvent queues an event of the manager.
+// Events may come from any goroutine including that of the app, which must not wait
+// for its own queued updates, so they are applied in order by pumpEvents.
+// Once the app has stopped, the events which do not fit in the queue are dropped.
+func (tui *Tui) handleEvent(e core.Event) {
+	select {
+	case tui.events <- e:
+	case <-tui.stopped:
 	}
 }
 
-func (tui *Tui) updateAllFeed() error {
-	length := len(tui.FeedWidget.Feeds)
-	doneCount := 0
-
-	wg := sync.WaitGroup{}
-
-	for index := range tui.FeedWidget.Feeds {
-		wg.Add(1)
-		go func(i int) {
-			if err := tui.updateFeed(i); err != nil {
-				if !errors.Is(err, ErrGettingFeedFailed) {
-					panic(err)
-				}
-			} else {
-				if err := tui.FeedWidget.SaveFeed(tui.FeedWidget.Feeds[i]); err != nil {
-					panic(err)
-				}
-			}
-			doneCount++
-			if doneCount == length {
-				tui.Notify("All feeds are up-to-date.")
-			} else {
-				tui.Notify(fmt.Sprint("Updating ", doneCount, "/", length, " feeds...\r"))
-			}
-			tui.App.ForceDraw()
-			wg.Done()
-		}(index)
+// syncManager runs fn, which changes the feeds, on the goroutine of the app so that
+// they are not drawn meanwhile, and waits for it. Once the app has stopped, fn runs right away.
+func (tui *Tui) syncManager(fn func()) {
+	once := &sync.Once{}
+	done := make(chan struct{})
+	go tui.App.QueueUpdateDraw(func() {
+		once.Do(fn)
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-tui.stopped:
+		once.Do(fn)
 	}
+}
 
-	wg.Wait()
+func (tui *Tui) pumpEvents() {
+	for e := range tui.events {
+		e := e
+		tui.App.QueueUpdateDraw(func() {
+			tui.applyEvent(e)
+		})
+	}
+}
 
-	for index := range tui.GroupWidget.Groups {
-		if err := tui.updateGroup(index); err != nil {
-			return err
+func (tui *Tui) applyEvent(e core.Event) {
+	switch e.Type {
+	case core.FeedsChanged:
+		tui.FeedWidget.setFeeds()
+	case core.GroupsChanged:
+		tui.GroupWidget.setGroups()
+	case core.FeedUpdated:
+		tui.Notify(fmt.Sprint("Updating ", e.Done, "/", e.Total, " feeds..."))
+	case core.UpdateFinished:
+		if e.Err != nil {
+			tui.NotifyError(e.Err.Error())
+		} else if e.Failed > 0 {
+			tui.NotifyError(fmt.Sprint(e.Failed, "/", e.Total, " feeds failed to update."))
+		} else {
+			tui.Notify("All feeds are up-to-date.")
+		}
+		if len(tui.Manager.Feeds) > 0 {
+			tui.FeedWidget.Table.ScrollToBeginning()
+		}
+		tui.GroupWidget.setGroups()
+		tui.FeedWidget.setFeeds()
+		tui.RefreshTui()
+	case core.DownloadProgress:
+		progress := formatSize(int64(e.Done))
+		if e.Total > 0 {
+			progress = fmt.Sprintf("%d%% of %s", e.Done*100/e.Total, formatSize(int64(e.Total)))
+		}
+		tui.Notify(fmt.Sprint("Downloading ", e.Item.Title, ": ", progress))
+	case core.DownloadFinished:
+		// the row is refreshed first as it clears the notification
+		tui.refreshItemRow(e.Item)
+		if e.Err != nil {
+			tui.NotifyError(e.Err.Error())
+		} else {
+			tui.Notify("Downloaded " + e.Item.Title + ".")
 		}
 	}
+}
 
-	if len(tui.FeedWidget.Feeds) > 0 {
-		if err := tui.GetTodaysFeeds(); err != nil {
-			return err
-		}
-		tui.FeedWidget.Table.ScrollToBeginning()
+func formatSize(n int64) string {
+	if s := fd.FormatBytes(n); s != "" {
+		return s
 	}
-	tui.GroupWidget.setGroups()
-	tui.FeedWidget.setFeeds()
-	tui.RefreshTui()
+	return "0 B"
+}
 
-	return nil
+// refreshItemRow redraws the row of item if it is shown.
+func (tui *Tui) refreshItemRow(item *fd.Item) {
+	for i, it := range tui.SubWidget.Items {
+		if it == item {
+			tui.SubWidget.Table.GetCell(i, 0).SetText(tui.itemTitles([]*fd.Item{item})[0])
+			if tui.App.GetFocus() == tui.SubWidget.Table {
+				tui.RefreshTui()
+			}
+			return
+		}
+	}
 }
 
 func (tui *Tui) selectGroupRow(row, column int) {
 	var feed *fd.Feed
 	tui.Notify("")
 	tui.ConfirmationStatus = defaultConfirmationStatus
-	if len(tui.GroupWidget.Groups) > 0 {
-		feed = tui.GroupWidget.Groups[row]
+	if len(tui.Manager.Groups) > 0 {
+		feed = tui.Manager.Groups[row]
 		tui.setItems(true, tui.LastSelectedWidget == tui.GroupWidget.Table)
 	}
 	if tui.App.GetFocus() == tui.GroupWidget.Table {
-		if len(tui.GroupWidget.Groups) > 0 {
+		if len(tui.Manager.Groups) > 0 {
 			feedStatus := [][]string{
 				{"Title:", feed.Title},
 				{"Link:", feed.Link},
@@ -390,17 +321,32 @@ func (tui *Tui) selectFeedRow(row, column int) {
 	var feed *fd.Feed
 	tui.Notify("")
 	tui.ConfirmationStatus = defaultConfirmationStatus
-	if len(tui.FeedWidget.Feeds) > 0 {
-		feed = tui.FeedWidget.Feeds[row]
+	if len(tui.Manager.Feeds) > 0 {
+		feed = tui.Manager.Feeds[row]
 		tui.setItems(false, tui.LastSelectedWidget == tui.FeedWidget.Table)
 	}
 	if tui.App.GetFocus() == tui.FeedWidget.Table {
-		if len(tui.FeedWidget.Feeds) > 0 {
+		if len(tui.Manager.Feeds) > 0 {
 			feedStatus := [][]string{
 				{"Title:", feed.Title},
 				{"Link:", feed.Link},
 				{"Description:", feed.Description},
 				{"Colorcode:", strconv.Itoa(feed.Color)},
+				{"Interval:", tui.Manager.Interval(feed).String()},
+			}
+			if feed.Status.IsBroken() {
+				lastSuccess := "never"
+				if !feed.Status.LastSuccess.IsZero() {
+					lastSuccess = feed.Status.LastSuccess.Format(statusTimeLayout)
+				}
+				feedStatus = append(feedStatus,
+					[]string{"Failures:", strconv.Itoa(feed.Status.Failures)},
+					[]string{"Last success:", lastSuccess},
+					[]string{"Next retry:", feed.Status.NextRetry.Format(statusTimeLayout)},
+				)
+			}
+			if feed.Status.LastError != "" {
+				feedStatus = append(feedStatus, []string{"Error:", feed.Status.LastError})
 			}
 			tui.showDescription(feedStatus)
 		}
@@ -409,105 +355,67 @@ func (tui *Tui) selectFeedRow(row, column int) {
 
 func (tui *Tui) selectSubRow(row, column int) {
 	var (
-		item       *fd.Item
-		parentFeed *fd.Feed
-		feedTitle  string
+		item      *fd.Item
+		feedTitle string
 	)
 
 	tui.Notify("")
 
-	if len(tui.SubWidget.Items) == 0 || len(tui.FeedWidget.Feeds) == 0 {
+	if len(tui.SubWidget.Items) == 0 || len(tui.Manager.Feeds) == 0 {
 		return
 	}
 
 	item = tui.SubWidget.Items[row]
 
-	index, _ := tui.FeedWidget.Table.GetSelection()
-	parentFeed = tui.FeedWidget.Feeds[index]
-
 	if tui.App.GetFocus() == tui.SubWidget.Table {
-		if parentFeed.IsMerged() {
-			for _, feed := range tui.FeedWidget.Feeds {
-				feedLink, err := feed.GetFeedLink()
-				if err != fd.ErrGetFeedLinkFailed {
-					if item.Belong == feedLink {
-						feedTitle = fmt.Sprint(feed.Title, "\n")
-					}
-				}
-			}
-		}
+		feedTitle = tui.feedTitle(item.Belong)
 		itemText := [][]string{
 			{"Feed:", feedTitle},
-			{"Published:", item.FormatDate()},
+			{"Published:", item.FormatDateAs(tui.Config.Dates.Description)},
 			{"Title:", item.Title},
 			{"Link:", item.Link},
 		}
-		tui.showDescription(itemText)
-	}
-}
-
-func (tui *Tui) AddFeedsFromURL(path string) error {
-	if !myio.IsFile(path) {
-		return nil
-	}
-
-	_, feedURLs, err := myio.GetLines(path)
-	if err != nil {
-		return err
-	}
-
-	fileNames := []string{}
-	for _, fp := range myio.DirWalk(getDataPath()) {
-		fileNames = append(fileNames, filepath.Base(fp))
-	}
-
-	newURLs := []string{}
-	for _, feedLink := range feedURLs {
-		isNewURL := true
-		hash := fmt.Sprintf("%x", md5.Sum([]byte(feedLink)))
-		for _, fileName := range fileNames {
-			if filepath.Base(fileName) == hash {
-				isNewURL = false
+		for _, e := range item.Enclosures {
+			itemText = append(itemText, []string{"Enclosure:", formatEnclosure(e)})
+			if e.Path != "" {
+				itemText = append(itemText, []string{"Downloaded:", e.Path})
 			}
 		}
-		if isNewURL {
-			newURLs = append(newURLs, feedLink)
+		if item.UnknownDate {
+			itemText = append(itemText, []string{"First seen:", item.PubDate.In(fd.Location).Format(tui.Config.Dates.Description)})
+		}
+		if len(item.Filters) > 0 {
+			itemText = append(itemText, []string{"Filters:", strings.Join(item.Filters, ", ")})
+		}
+		if item.Archived {
+			itemText = append(itemText, []string{"Archived:", "no longer in the feed"})
 		}
+		tui.showDescription(itemText)
 	}
+}
 
-	for _, url := range newURLs {
-		f := &fd.Feed{
-			Title:       "getting " + url + "...",
-			Color:       -1,
-			Description: "update to get details",
-			Link:        "",
-			FeedLinks:   []string{url},
-			Items:       []*fd.Item{},
-		}
-		tui.FeedWidget.Feeds = append(tui.FeedWidget.Feeds, f)
+func (tui *Tui) openItem(row int) {
+	if len(tui.SubWidget.Items) == 0 {
+		return
 	}
-	tui.FeedWidget.setFeeds()
+	item := tui.SubWidget.Items[row]
 
-	return nil
-}
+	target := opener.Target{URL: item.Link, Title: item.Title, Feed: tui.feedTitle(item.Belong)}
+	if err := tui.open(target); err != nil {
+		tui.NotifyError(err.Error())
+		return
+	}
 
-func (tui *Tui) LoadFeeds(path string) error {
-	for _, file := range myio.DirWalk(path) {
-		b, err := ioutil.ReadFile(file)
-		if err != nil {
-			return err
-		}
-		feed := fd.DecodeFeed(b)
-		if feed.IsMerged() {
-			tui.GroupWidget.Groups = append(tui.GroupWidget.Groups, feed)
-		} else {
-			tui.FeedWidget.Feeds = append(tui.FeedWidget.Feeds, feed)
-		}
+	if err := tui.Manager.MarkRead(item); err != nil {
+		tui.NotifyError(err.Error())
+		return
 	}
-	return nil
+	tui.SubWidget.Table.GetCell(row, 0).SetAttributes(tcell.AttrNone)
 }
 
-func NewTui() *Tui {
+func NewTui(conf *config.Config) *Tui {
+	manager := core.NewManagerWithConfig(conf)
+	layout := conf.Layout
 
 	groupTable := tview.NewTable()
 	groupTable.SetTitle(groupWidgetTitle).SetBorder(true).SetTitleAlign(tview.AlignLeft)
@@ -538,27 +446,28 @@ func NewTui() *Tui {
 	mainFlex := tview.NewFlex().SetDirection(tview.FlexRow).
 		AddItem(tview.NewFlex().SetDirection(tview.FlexColumn).
 			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
-				AddItem(groupTable, 0, 2, false).
-				AddItem(feedTable, 0, 2, false).
-				AddItem(infoWidget, 0, 1, false),
-				0, 1, false).
+				AddItem(groupTable, 0, layout.GroupsHeight, false).
+				AddItem(feedTable, 0, layout.FeedsHeight, false).
+				AddItem(infoWidget, 0, layout.InfoHeight, false),
+				0, layout.SideWidth, false).
 			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
-				AddItem(subTable, 0, 3, false).
-				AddItem(descriptionWidget, 0, 1, false),
-				0, 2, false),
+				AddItem(subTable, 0, layout.ItemsHeight, false).
+				AddItem(descriptionWidget, 0, layout.DescriptionHeight, false),
+				0, layout.MainWidth, false),
 			0, 1, false).AddItem(helpWidget, 1, 0, false)
 
+	// the description page swaps the heights of the items and the description
 	descriptionFlex := tview.NewFlex().SetDirection(tview.FlexRow).
 		AddItem(tview.NewFlex().SetDirection(tview.FlexColumn).
 			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
-				AddItem(groupTable, 0, 2, false).
-				AddItem(feedTable, 0, 2, false).
-				AddItem(infoWidget, 0, 1, false),
-				0, 1, false).
+				AddItem(groupTable, 0, layout.GroupsHeight, false).
+				AddItem(feedTable, 0, layout.FeedsHeight, false).
+				AddItem(infoWidget, 0, layout.InfoHeight, false),
+				0, layout.SideWidth, false).
 			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
-				AddItem(subTable, 0, 1, false).
-				AddItem(descriptionWidget, 0, 3, false),
-				0, 2, false),
+				AddItem(subTable, 0, layout.DescriptionHeight, false).
+				AddItem(descriptionWidget, 0, layout.ItemsHeight, false),
+				0, layout.MainWidth, false),
 			0, 1, false).AddItem(helpWidget, 1, 0, false)
 
 	inputFlex := tview.NewFlex().
@@ -569,6 +478,15 @@ func NewTui() *Tui {
 			AddItem(nil, 0, 1, false), 40, 1, false).
 		AddItem(nil, 0, 1, false)
 
+	readerWidget := tview.NewTextView()
+	readerWidget.SetBorder(true).SetTitleAlign(tview.AlignLeft)
+	readerWidget.SetDynamicColors(true).SetWordWrap(true)
+
+	readerFlex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(readerWidget, 0, 1, false).
+		AddItem(infoWidget, 3, 0, false).
+		AddItem(helpWidget, 1, 0, false)
+
 	modal := tview.NewModal()
 	modal.SetBorder(true).SetTitleAlign(0)
 	modal.SetBackgroundColor(tcell.ColorBlack)
@@ -576,14 +494,15 @@ func NewTui() *Tui {
 	pages := tview.NewPages().
 		AddPage(mainPage, mainFlex, true, true).
 		AddPage(descriptionPage, descriptionFlex, true, false).
+		AddPage(readerPage, readerFlex, true, false).
 		AddPage(inputField, inputFlex, true, false).
 		AddPage(modalPage, modal, true, false)
 
 	tui := &Tui{
 		App:                tview.NewApplication(),
 		Pages:              pages,
-		GroupWidget:        &GroupWidget{groupTable, []*fd.Feed{}},
-		FeedWidget:         &FeedWidget{feedTable, []*fd.Feed{}},
+		GroupWidget:        &GroupWidget{groupTable, manager},
+		FeedWidget:         &FeedWidget{feedTable, manager},
 		SubWidget:          &SubWidget{subTable, []*fd.Item{}},
 		Description:        descriptionWidget,
 		Info:               infoWidget,
@@ -594,8 +513,16 @@ func NewTui() *Tui {
 		ConfirmationStatus: defaultConfirmationStatus,
 		LastSelectedWidget: feedTable,
 		Modal:              modal,
+		Reader:             readerWidget,
+		Manager:            manager,
+		Config:             conf,
+		events:             make(chan core.Event, eventQueueSize),
+		stopped:            make(chan struct{}),
 	}
 
+	manager.Sync = tui.syncManager
+	manager.Subscribe(tui.handleEvent)
+	go tui.pumpEvents()
 	tui.setAppFunctions()
 
 	return tui
@@ -611,16 +538,24 @@ func (tui *Tui) setAppFunctions() {
 		case tcell.KeyRune:
 			switch event.Rune() {
 			case 'R':
-				if err := tui.updateAllFeed(); err != nil {
-					panic(err)
-				}
+				tui.updateAllFeedAsync()
 				return nil
 			case 'r':
-				tui.InputWidget.Input.SetTitle("rename the feed")
+				tui.InputWidget.Input.SetTitle("rename the group")
 				tui.InputWidget.Mode = 4
 				tui.Pages.ShowPage(inputField)
 				tui.App.SetFocus(tui.InputWidget.Input)
 				return nil
+			case '[', ']':
+				delta := -1
+				if event.Rune() == ']' {
+					delta = 1
+				}
+				if _, err := tui.GroupWidget.MoveSelection(delta); err != nil {
+					tui.NotifyError(err.Error())
+				}
+				tui.RefreshTui()
+				return nil
 			case 'l':
 				tui.LastSelectedWidget = tui.GroupWidget.Table
 				tui.App.SetFocus(tui.SubWidget.Table)
@@ -633,9 +568,9 @@ func (tui *Tui) setAppFunctions() {
 			case 'd':
 				if tui.ConfirmationStatus == 'd' {
 					if err := tui.GroupWidget.DeleteSelection(); err != nil {
-						if !errors.Is(err, ErrRmFailed) {
-							panic(err)
-						}
+						tui.NotifyError(err.Error())
+						tui.ConfirmationStatus = defaultConfirmationStatus
+						return nil
 					}
 					tui.GroupWidget.setGroups()
 					tui.RefreshTui()
@@ -647,10 +582,12 @@ func (tui *Tui) setAppFunctions() {
 				}
 			case 'x':
 				texts := []string{
-					"d: delete selecting feed",
+					"d: delete selecting group",
 					"l: move to FeedColumn",
-					"r: rename selecting feed",
+					"r: rename selecting group",
+					"[/]: move selecting group up/down",
 					"R: reload feeds",
+					"F: search all items",
 					"q: Exit rfcui",
 				}
 				text := ""
@@ -674,9 +611,7 @@ func (tui *Tui) setAppFunctions() {
 		case tcell.KeyRune:
 			switch event.Rune() {
 			case 'R':
-				if err := tui.updateAllFeed(); err != nil {
-					panic(err)
-				}
+				tui.updateAllFeedAsync()
 				return nil
 			case 'r':
 				tui.InputWidget.Input.SetTitle("rename the feed")
@@ -684,6 +619,16 @@ func (tui *Tui) setAppFunctions() {
 				tui.Pages.ShowPage(inputField)
 				tui.App.SetFocus(tui.InputWidget.Input)
 				return nil
+			case 't':
+				tui.InputWidget.Input.SetTitle("refresh interval")
+				tui.InputWidget.Mode = 5
+				tui.Pages.ShowPage(inputField)
+				tui.App.SetFocus(tui.InputWidget.Input)
+				tui.Notify("Enter an interval like 90m or 6h, or 0 to use the default.")
+				return nil
+			case 'b':
+				tui.showBrokenFeeds()
+				return nil
 			case 'l':
 				tui.LastSelectedWidget = tui.FeedWidget.Table
 				tui.App.SetFocus(tui.SubWidget.Table)
@@ -697,17 +642,14 @@ func (tui *Tui) setAppFunctions() {
 				tui.SelectFeed()
 			case 'c':
 				if tui.ConfirmationStatus == 'c' {
-					number := int(mycolor.ComfortableColorCode[rand.Intn(len(mycolor.ComfortableColorCode))])
 					row, _ := tui.FeedWidget.Table.GetSelection()
-					tui.FeedWidget.Feeds[row].Color = number
-					for _, item := range tui.FeedWidget.Feeds[row].Items {
-						item.Color = number
-					}
-					if err := tui.FeedWidget.SaveFeed(tui.FeedWidget.Feeds[row]); err != nil {
-						panic(err)
+					if err := tui.Manager.SetRandomColor(tui.Manager.Feeds[row]); err != nil {
+						tui.NotifyError(err.Error())
+						tui.ConfirmationStatus = defaultConfirmationStatus
+						return nil
 					}
 					tui.FeedWidget.setFeeds()
-					tui.setItems(tui.FeedWidget.Feeds[row].IsMerged(), false)
+					tui.setItems(tui.Manager.Feeds[row].IsMerged(), false)
 					tui.Notify("Changed.")
 					tui.ConfirmationStatus = defaultConfirmationStatus
 				} else {
@@ -715,21 +657,21 @@ func (tui *Tui) setAppFunctions() {
 					tui.ConfirmationStatus = 'c'
 				}
 			case 'm':
-				if len(tui.SelectingFeeds) > 1 {
+				if len(tui.SelectingFeeds) > 0 {
 					tui.InputWidget.Input.SetTitle("Make a Group")
 					tui.InputWidget.Mode = 1
 					tui.Pages.ShowPage(inputField)
 					tui.App.SetFocus(tui.InputWidget.Input)
 				} else {
-					tui.Notify("Select at least 2 feeds with the v key to make a group.")
+					tui.Notify("Select feeds with the v key to make a group.")
 				}
 				return nil
 			case 'd':
 				if tui.ConfirmationStatus == 'd' {
 					if err := tui.FeedWidget.DeleteSelection(); err != nil {
-						if !errors.Is(err, ErrRmFailed) {
-							panic(err)
-						}
+						tui.NotifyError(err.Error())
+						tui.ConfirmationStatus = defaultConfirmationStatus
+						return nil
 					}
 					tui.FeedWidget.setFeeds()
 					tui.Notify("Deleted.")
@@ -740,19 +682,13 @@ func (tui *Tui) setAppFunctions() {
 				}
 			case 'u':
 				row, _ := tui.FeedWidget.Table.GetSelection()
-				selectedFeed := tui.FeedWidget.Feeds[row]
+				selectedFeed := tui.Manager.Feeds[row]
 				if tui.ConfirmationStatus == 'u' {
-					feedLink, _ := selectedFeed.GetFeedLink()
-					feed, err := fd.GetFeedFromURL(feedLink, "")
-					if err != nil {
-						panic(err)
-					}
-					selectedFeed.Title = feed.Title
-
-					if err := tui.FeedWidget.SaveFeed(selectedFeed); err != nil {
-						panic(err)
+					if err := tui.Manager.ResetTitle(selectedFeed); err != nil {
+						tui.NotifyError(err.Error())
+						tui.ConfirmationStatus = defaultConfirmationStatus
+						return nil
 					}
-
 					tui.FeedWidget.setFeeds()
 					tui.Notify("Reset.")
 					tui.ConfirmationStatus = defaultConfirmationStatus
@@ -762,19 +698,13 @@ func (tui *Tui) setAppFunctions() {
 				}
 			case 'e':
 				if tui.ConfirmationStatus == 'e' {
-					listFile, err := os.Create(exportListPath)
-					if err != nil {
-						panic(err)
-					}
-					defer listFile.Close()
-
-					for _, feed := range tui.FeedWidget.Feeds {
-						if _, err := listFile.WriteString(feed.FeedLinks[0] + "\n"); err != nil {
-							panic(err)
-						}
+					if err := tui.Manager.ExportList(exportListPath()); err != nil {
+						tui.NotifyError(err.Error())
+						tui.ConfirmationStatus = defaultConfirmationStatus
+						return nil
 					}
 
-					tui.Notify("Exported to " + exportListPath + ".")
+					tui.Notify("Exported to " + exportListPath() + ".")
 					tui.ConfirmationStatus = defaultConfirmationStatus
 				} else {
 					tui.Notify("Press e again to export feed urls.")
@@ -782,32 +712,60 @@ func (tui *Tui) setAppFunctions() {
 				}
 			case 'i':
 				if tui.ConfirmationStatus == 'i' {
-					if err := tui.AddFeedsFromURL(importListPath); err != nil {
-						panic(err)
+					if err := tui.Manager.ImportList(importListPath()); err != nil {
+						tui.NotifyError(err.Error())
+						tui.ConfirmationStatus = defaultConfirmationStatus
+						return nil
 					}
 
-					tui.WaitGroup.Add(1)
-					go func() {
-						if err := tui.updateAllFeed(); err != nil {
-							panic(err)
-						}
-						tui.App.QueueUpdateDraw(func() {})
-						tui.WaitGroup.Done()
-					}()
+					tui.updateAllFeedAsync()
 
-					tui.Notify("Imported from " + importListPath + ".")
+					tui.Notify("Imported from " + importListPath() + ".")
 					tui.ConfirmationStatus = defaultConfirmationStatus
 				} else {
-					tui.Notify("Press i again to import from " + importListPath + ".")
+					tui.Notify("Press i again to import from " + importListPath() + ".")
 					tui.ConfirmationStatus = 'i'
 				}
+			case 'E':
+				if tui.ConfirmationStatus == 'E' {
+					if err := tui.Manager.ExportOPML(exportOPMLPath()); err != nil {
+						tui.NotifyError(err.Error())
+					} else {
+						tui.Notify("Exported to " + exportOPMLPath() + ".")
+					}
+					tui.ConfirmationStatus = defaultConfirmationStatus
+				} else {
+					tui.Notify("Press E again to export feeds as OPML.")
+					tui.ConfirmationStatus = 'E'
+				}
+			case 'I':
+				if tui.ConfirmationStatus == 'I' {
+					if err := tui.Manager.ImportOPML(importOPMLPath()); err != nil {
+						tui.NotifyError(err.Error())
+						tui.ConfirmationStatus = defaultConfirmationStatus
+						return nil
+					}
+
+					tui.updateAllFeedAsync()
+
+					tui.Notify("Imported from " + importOPMLPath() + ".")
+					tui.ConfirmationStatus = defaultConfirmationStatus
+				} else {
+					tui.Notify("Press I again to import from " + importOPMLPath() + ".")
+					tui.ConfirmationStatus = 'I'
+				}
 			case 'x':
 				texts := []string{
+					"b: show broken feeds",
 					"c: recolor selecting feed",
 					"d: delete selecting feed",
+					"E: export feeds as OPML",
 					"h: move to GroupColumn",
+					"I: import feeds from OPML",
 					"r: rename selecting feed",
 					"R: reload feeds",
+					"t: set refresh interval of selecting feed",
+					"F: search all items",
 					"q: Exit rfcui",
 				}
 				text := ""
@@ -830,17 +788,35 @@ func (tui *Tui) setAppFunctions() {
 			switch event.Key() {
 			case tcell.KeyEnter:
 				row, _ := tui.SubWidget.Table.GetSelection()
-				browser := os.Getenv("BROWSER")
-				if browser == "" {
-					tui.Notify("$BROWSER is empty. Set it and try again.")
-				} else {
-					if err := execCmd(true, browser, tui.SubWidget.Items[row].Link); err != nil {
-						panic(err)
-					}
+				tui.openItem(row)
+				return nil
+			case tcell.KeyESC:
+				if tui.Search != nil {
+					tui.clearSearch()
+					tui.Notify("")
 				}
 				return nil
 			case tcell.KeyRune:
 				switch event.Rune() {
+				case '/':
+					tui.startSearch()
+					return nil
+				case 'n':
+					tui.jumpToMatch(true)
+					return nil
+				case 'N':
+					tui.jumpToMatch(false)
+					return nil
+				case 'w':
+					tui.startSaveSearch()
+					return nil
+				case 's':
+					row, _ := tui.SubWidget.Table.GetSelection()
+					tui.toggleStar(row)
+					return nil
+				case 'H':
+					tui.toggleHidden()
+					return nil
 				case 'l':
 					tui.Pages.SwitchToPage(descriptionPage)
 					tui.App.SetFocus(tui.Description)
@@ -852,18 +828,28 @@ func (tui *Tui) setAppFunctions() {
 					return nil
 				case 'o':
 					row, _ := tui.SubWidget.Table.GetSelection()
-					browser := os.Getenv("BROWSER")
-					if browser == "" {
-						tui.Notify("$BROWSER is empty. Set it and try again.")
-					} else {
-						if err := execCmd(true, browser, tui.SubWidget.Items[row].Link); err != nil {
-							panic(err)
-						}
-					}
+					tui.openItem(row)
+					return nil
+				case 'v':
+					row, _ := tui.SubWidget.Table.GetSelection()
+					tui.readItem(row)
+					return nil
+				case 'D':
+					row, _ := tui.SubWidget.Table.GetSelection()
+					tui.downloadItem(row)
 					return nil
 				case 'x':
 					texts := []string{
 						"l: move to DescriptionColumn",
+						"o: open selecting item in the browser",
+						"v: read selecting item",
+						"D: download enclosures of selecting item",
+						"s: star/unstar selecting item",
+						"/: search items",
+						"n/N: jump to next/previous match",
+						"w: save search as a group",
+						"H: show/hide the items hidden by the filters",
+						"Esc: clear search",
 						"h: move to MainColumn",
 						"q: Exit rfcui",
 					}
@@ -906,97 +892,155 @@ func (tui *Tui) setAppFunctions() {
 		return event
 	})
 
-	tui.InputWidget.Input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+	tui.Reader.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		switch event.Key() {
 		case tcell.KeyESC:
+			tui.closeReader()
+			return nil
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'h':
+				tui.closeReader()
+				return nil
+			case 'o':
+				tui.showLinkInput("open link", 6, "Enter the number of the link to open.")
+				return nil
+			case 'y':
+				tui.showLinkInput("copy link", 7, "Enter the number of the link to copy.")
+				return nil
+			case '|':
+				tui.showLinkInput("pipe link", 8, "Enter the number of the link and a command like: 3 mpv")
+				return nil
+			case 'x':
+				texts := []string{
+					"h: back to items",
+					"o: open a link by number",
+					"y: copy a link by number",
+					"|: pipe a link by number to a command",
+					"j/k: scroll",
+					"g/G: go to the top/bottom",
+					"q: Exit rfcui",
+				}
+				text := ""
+				for _, line := range texts {
+					text += line + "\n"
+				}
+				tui.Modal.SetTitle("keymaps")
+				tui.Modal.SetText(text)
+				tui.Pages.ShowPage(modalPage)
+				tui.App.SetFocus(tui.Modal)
+				return nil
+			}
+		}
+		return event
+	})
+
+	tui.InputWidget.Input.SetChangedFunc(func(text string) {
+		if tui.InputWidget.Mode == searchMode {
+			tui.search(text)
+		}
+	})
+
+	tui.InputWidget.Input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if tui.InputWidget.Mode == searchMode {
+			switch event.Key() {
+			case tcell.KeyESC:
+				tui.finishSearch(true)
+				return nil
+			case tcell.KeyEnter:
+				tui.finishSearch(false)
+				return nil
+			}
+			return event
+		}
+		if tui.InputWidget.Mode == globalSearchMode && event.Key() == tcell.KeyEnter {
+			text := tui.InputWidget.Input.GetText()
 			tui.InputWidget.Input.SetText("")
 			tui.InputWidget.Input.SetTitle("Input")
 			tui.Pages.HidePage(inputField)
 			tui.App.SetFocus(tui.FeedWidget.Table)
+			tui.showSearchResults(text)
+			return nil
+		}
+
+		switch event.Key() {
+		case tcell.KeyESC:
+			tui.InputWidget.Input.SetText("")
+			tui.InputWidget.Input.SetTitle("Input")
+			tui.Pages.HidePage(inputField)
+			tui.focusFrontPage()
 			tui.Notify("")
 			return nil
 		case tcell.KeyEnter:
 			switch tui.InputWidget.Mode {
 			case 0: // new feed
-				if err := tui.AddFeedFromURL(tui.InputWidget.Input.GetText()); err != nil {
+				if _, err := tui.Manager.AddFeed(tui.InputWidget.Input.GetText()); err != nil {
 					tui.NotifyError(err.Error())
 				}
-				tui.WaitGroup.Add(1)
-				go func() {
-					if err := tui.updateAllFeed(); err != nil {
-						panic(err)
-					}
-					tui.App.QueueUpdateDraw(func() {})
-					tui.WaitGroup.Done()
-				}()
+				tui.updateAllFeedAsync()
 			case 1: // merge feeds
 				title := tui.InputWidget.Input.GetText()
-				existIndex := -1
-				for i, feed := range tui.GroupWidget.Groups {
-					if feed.IsMerged() && title == feed.Title {
-						existIndex = i
-						break
+				feedLinks := []string{}
+				for _, f := range tui.SelectingFeeds {
+					if feedLink, err := f.GetFeedLink(); err == nil {
+						feedLinks = append(feedLinks, feedLink)
 					}
 				}
-
-				if existIndex != -1 {
-					for _, f := range tui.SelectingFeeds {
-						isNewFeedLink := true
-						feedLink, _ := f.GetFeedLink()
-						for _, url := range tui.GroupWidget.Groups[existIndex].FeedLinks {
-							if feedLink == url {
-								isNewFeedLink = false
-								break
-							}
-						}
-						if isNewFeedLink {
-							tui.GroupWidget.Groups[existIndex].FeedLinks = append(tui.GroupWidget.Groups[existIndex].FeedLinks, feedLink)
-						}
-					}
-				} else {
-					mergedFeed, err := fd.MergeFeeds(tui.SelectingFeeds, title)
-					if err != nil {
-						panic(err)
-					}
-					tui.GroupWidget.Groups = append(tui.GroupWidget.Groups, mergedFeed)
-					if err := tui.GroupWidget.SaveGroup(mergedFeed); err != nil {
-						panic(err)
-					}
+				if _, err := tui.Manager.AddGroup(title, feedLinks); err != nil {
+					tui.NotifyError(err.Error())
 				}
-
-				tui.WaitGroup.Add(1)
-				go func() {
-					if err := tui.updateAllFeed(); err != nil {
-						panic(err)
-					}
-					tui.App.QueueUpdateDraw(func() {})
-					tui.WaitGroup.Done()
-				}()
+				tui.updateAllFeedAsync()
 			case 3:
 				title := tui.InputWidget.Input.GetText()
 				row, _ := tui.FeedWidget.Table.GetSelection()
-				selectedFeed := tui.FeedWidget.Feeds[row]
-				if selectedFeed.IsMerged() {
-					// rename the cache file
-					oldFileName := filepath.Join(getDataPath(), fmt.Sprintf("%x", md5.Sum([]byte(selectedFeed.Title))))
-					newFileName := filepath.Join(getDataPath(), fmt.Sprintf("%x", md5.Sum([]byte(title))))
-					if err := os.Rename(oldFileName, newFileName); err != nil {
-						panic(err)
-					}
-				}
-				selectedFeed.Title = title
-				if err := tui.FeedWidget.SaveFeed(selectedFeed); err != nil {
-					panic(err)
+				if err := tui.Manager.RenameFeed(tui.Manager.Feeds[row], title); err != nil {
+					tui.NotifyError(err.Error())
 				}
 				tui.FeedWidget.setFeeds()
 			case 4:
-				tui.Notify("まだ実装してない")
+				name := tui.InputWidget.Input.GetText()
+				row, _ := tui.GroupWidget.Table.GetSelection()
+				err := tui.Manager.RenameGroup(tui.Manager.Groups[row], name)
+				tui.GroupWidget.setGroups()
+				if err != nil {
+					tui.NotifyError(err.Error())
+				}
+			case 5:
+				interval, err := time.ParseDuration(tui.InputWidget.Input.GetText())
+				if err != nil || interval < 0 {
+					tui.NotifyError("invalid interval: " + tui.InputWidget.Input.GetText())
+					break
+				}
+				row, _ := tui.FeedWidget.Table.GetSelection()
+				if err := tui.Manager.SetRefreshInterval(tui.Manager.Feeds[row], interval); err != nil {
+					tui.NotifyError(err.Error())
+					break
+				}
+				tui.Notify("Changed.")
+			case 6:
+				if err := tui.openLink(tui.InputWidget.Input.GetText()); err != nil {
+					tui.NotifyError(err.Error())
+				}
+			case 7:
+				if err := tui.copyLink(tui.InputWidget.Input.GetText()); err != nil {
+					tui.NotifyError(err.Error())
+				} else {
+					tui.Notify("Copied.")
+				}
+			case 8:
+				if err := tui.pipeLink(tui.InputWidget.Input.GetText()); err != nil {
+					tui.NotifyError(err.Error())
+				} else {
+					tui.Notify("")
+				}
+			case saveSearchMode:
+				tui.saveSearch(tui.InputWidget.Input.GetText())
 			}
 			tui.SelectingFeeds = []*fd.Feed{}
 			tui.InputWidget.Input.SetText("")
 			tui.InputWidget.Input.SetTitle("Input")
 			tui.Pages.HidePage(inputField)
-			tui.App.SetFocus(tui.FeedWidget.Table)
+			tui.focusFrontPage()
 			return nil
 		}
 		return event
@@ -1005,7 +1049,7 @@ func (tui *Tui) setAppFunctions() {
 	tui.Modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		tui.Pages.HidePage(modalPage)
 		tui.Modal.SetText("")
-		tui.App.SetFocus(tui.FeedWidget.Table)
+		tui.focusFrontPage()
 		return event
 	})
 
@@ -1017,12 +1061,21 @@ func (tui *Tui) setAppFunctions() {
 		case tcell.KeyRune:
 			switch event.Rune() {
 			case 'n':
+				// n jumps to the next match while searching items
+				if tui.isSearching() {
+					return event
+				}
 				tui.InputWidget.Input.SetTitle("New Feed")
 				tui.InputWidget.Mode = 0
 				tui.Pages.ShowPage(inputField)
 				tui.App.SetFocus(tui.InputWidget.Input)
 				tui.Notify("Enter a feed URL or a command to output feed as xml.")
 				return nil
+			case 'F':
+				if name, _ := tui.Pages.GetFrontPage(); name == mainPage {
+					tui.startGlobalSearch()
+					return nil
+				}
 			case 'q':
 				tui.App.Stop()
 				return nil
@@ -1054,38 +1107,215 @@ func execCmd(attachStd bool, cmd string, args ...string) error {
 func (tui *Tui) Run() error {
 	fmt.Print("loading...\r")
 
-	if !myio.IsDir(cachePath) {
-		if err := os.MkdirAll(cachePath, 0755); err != nil {
-			return err
-		}
-	}
-
-	if err := tui.LoadFeeds(cachePath); err != nil {
+	if err := tui.Manager.Load(); err != nil {
 		return err
 	}
+	defer tui.Manager.Close()
 
+	// the updates are stopped and waited for, however the app exits, before the store is closed
+	stop := make(chan struct{})
+	defer func() {
+		close(tui.stopped)
+		close(stop)
+		tui.WaitGroup.Wait()
+	}()
+
+	tui.updateAllFeedAsync()
 	tui.WaitGroup.Add(1)
 	go func() {
-		if err := tui.updateAllFeed(); err != nil {
-			panic(err)
-		}
-		tui.App.QueueUpdateDraw(func() {})
-		tui.WaitGroup.Done()
+		defer tui.WaitGroup.Done()
+		tui.Manager.RunScheduler(stop)
 	}()
 
 	tui.App.SetRoot(tui.Pages, true)
 
-	if len(tui.GroupWidget.Groups) > 0 {
+	if len(tui.Manager.Groups) > 0 {
 		tui.App.SetFocus(tui.GroupWidget.Table)
 	} else {
 		tui.App.SetFocus(tui.FeedWidget.Table)
 	}
+	if len(tui.Manager.Corruptions) > 0 {
+		tui.showCorruptions()
+	}
 
 	if err := tui.App.Run(); err != nil {
-		tui.WaitGroup.Wait()
 		tui.App.Stop()
 		return err
 	}
 
 	return nil
 }
+
+func (tui *Tui) showBrokenFeeds() {
+	broken := tui.Manager.BrokenFeeds()
+	if len(broken) == 0 {
+		tui.Notify("No broken feeds.")
+		return
+	}
+
+	text := ""
+	for _, f := range broken {
+		text += fmt.Sprintf("%s (%d failures)\n%s\n\n", f.Title, f.Status.Failures, f.Status.LastError)
+	}
+	tui.Modal.SetTitle("broken feeds")
+	tui.Modal.SetText(text)
+	tui.Pages.ShowPage(modalPage)
+	tui.App.SetFocus(tui.Modal)
+}
+
+// showCorruptions tells what was found broken at startup.
+func (tui *Tui) showCorruptions() {
+	text := "These were broken and have been put into quarantine:\n\n"
+	for _, c := range tui.Manager.Corruptions {
+		text += c.String() + "\n"
+	}
+	tui.Modal.SetTitle("broken data")
+	tui.Modal.SetText(text)
+	tui.Pages.ShowPage(modalPage)
+	tui.App.SetFocus(tui.Modal)
+}
+
+// itemTitles returns the titles of items, prefixed with their dates if configured.
+func (tui *Tui) itemTitles(items []*fd.Item) []string {
+	layout := tui.Config.Dates.Items
+	titles := make([]string, len(items))
+	for i, item := range items {
+		titles[i] = item.Title
+		if item.IsDownloaded() {
+			titles[i] = downloadedMark + titles[i]
+		}
+		if item.Starred {
+			titles[i] = starredMark + titles[i]
+		}
+	}
+	if layout == "" {
+		return titles
+	}
+
+	dates := make([]string, len(items))
+	width := 0
+	for i, item := range items {
+		dates[i] = item.FormatDateAs(layout)
+		if w := runewidth.StringWidth(dates[i]); w > width {
+			width = w
+		}
+	}
+	for i := range items {
+		titles[i] = runewidth.FillRight(dates[i], width) + "  " + titles[i]
+	}
+	return titles
+}
+
+// focusFrontPage gives the focus back to the page under a popup.
+func (tui *Tui) focusFrontPage() {
+	if name, _ := tui.Pages.GetFrontPage(); name == readerPage {
+		tui.App.SetFocus(tui.Reader)
+	} else {
+		tui.App.SetFocus(tui.FeedWidget.Table)
+	}
+}
+
+func formatEnclosure(e *fd.Enclosure) string {
+	details := []string{}
+	if e.Type != "" {
+		details = append(details, e.Type)
+	}
+	if size := e.FormatSize(); size != "" {
+		details = append(details, size)
+	}
+	if len(details) == 0 {
+		return e.URL
+	}
+	return fmt.Sprintf("%s (%s)", e.URL, strings.Join(details, ", "))
+}
+
+func (tui *Tui) toggleStar(row int) {
+	if len(tui.SubWidget.Items) == 0 {
+		return
+	}
+	item := tui.SubWidget.Items[row]
+	if err := tui.Manager.ToggleStar(item); err != nil {
+		tui.NotifyError(err.Error())
+		return
+	}
+	tui.refreshItemRow(item)
+	if item.Starred {
+		tui.Notify("Starred.")
+	} else {
+		tui.Notify("Unstarred.")
+	}
+}
+
+func (tui *Tui) downloadItem(row int) {
+	if len(tui.SubWidget.Items) == 0 {
+		return
+	}
+	item := tui.SubWidget.Items[row]
+	if err := tui.Manager.Download(item); err != nil {
+		tui.NotifyError(err.Error())
+		return
+	}
+	tui.Notify(fmt.Sprint("Queued. ", tui.Manager.QueuedDownloads(), " downloads in the queue."))
+}
+
+// toggleHidden shows or hides the items hidden by the filters in the items pane.
+func (tui *Tui) toggleHidden() {
+	tui.ShowHidden = !tui.ShowHidden
+
+	groupRow, _ := tui.GroupWidget.Table.GetSelection()
+	feedRow, _ := tui.FeedWidget.Table.GetSelection()
+	switch {
+	case tui.LastSelectedWidget == tui.GroupWidget.Table && groupRow < len(tui.Manager.Groups):
+		tui.renderItems(tui.Manager.GroupItems(tui.Manager.Groups[groupRow]), true)
+	case tui.LastSelectedWidget == tui.FeedWidget.Table && feedRow < len(tui.Manager.Feeds):
+		tui.renderItems(tui.Manager.Feeds[feedRow].Items, tui.Manager.Feeds[feedRow].IsMerged())
+	}
+	if row, _ := tui.SubWidget.Table.GetSelection(); row >= tui.SubWidget.Table.GetRowCount() {
+		tui.SubWidget.Table.Select(tui.SubWidget.Table.GetRowCount()-1, 0)
+	}
+	tui.RefreshTui()
+
+	if tui.ShowHidden {
+		tui.Notify("Showing the items hidden by the filters.")
+	} else {
+		tui.Notify("Leaving out the items hidden by the filters.")
+	}
+}
+
+// renderItems shows items in the items pane, leaving out those hidden by the filters unless ShowHidden.
+func (tui *Tui) renderItems(items []*fd.Item, paintColor bool) {
+	if !tui.ShowHidden {
+		visible := []*fd.Item{}
+		for _, item := range items {
+			if !item.Hidden {
+				visible = append(visible, item)
+			}
+		}
+		items = visible
+	}
+	tui.SubWidget.Items = items
+
+	titles := tui.itemTitles(items)
+	table := tui.SubWidget.Table.Clear()
+	for i, item := range items {
+		table.SetCellSimple(i, 0, titles[i])
+		if paintColor && item.Color > 0 && item.Color < len(mycolor.TcellColors) {
+			table.GetCell(i, 0).SetTextColor(mycolor.TcellColors[item.Color])
+		}
+		attributes := tcell.AttrNone
+		if !item.Read {
+			attributes |= tcell.AttrBold
+		}
+		if item.Highlighted {
+			attributes |= tcell.AttrUnderline
+		}
+		if item.Hidden {
+			attributes |= tcell.AttrDim
+		}
+		table.GetCell(i, 0).SetAttributes(attributes)
+	}
+
+	if tui.Search != nil {
+		tui.highlightMatches()
+	}
+}